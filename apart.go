@@ -0,0 +1,590 @@
+package sm
+
+import (
+	"fmt"
+	"go/token"
+	"math/big"
+
+	goast "go/ast"
+)
+
+// apart is partial fraction decomposition of rational function by
+// variable.
+//
+//	apart(1/(x*x-1), x) = 0.500/(x-1.000) - 0.500/(x+1.000)
+func (s *sm) apart(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != apartName {
+		return false, nil, nil
+	}
+	if len(call.Args) != 2 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     apartName,
+			Args:     len(call.Args),
+			Expect:   2,
+		})
+	}
+	variable, ok := unparen(call.Args[1]).(*goast.Ident)
+	if !ok {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      fmt.Sprintf("not valid name of variable: %s", astToStr(call.Args[1])),
+		})
+	}
+	// polynomial and expression independent of variable are not changed
+	//
+	// from : apart(x*x+1, x)
+	// to   : x*x+1
+	if s.independent(call.Args[0], variable.Name) {
+		return true, call.Args[0], nil
+	}
+	if _, ok, err := s.polyOf(call.Args[0], variable.Name); err != nil {
+		return false, nil, err
+	} else if ok {
+		return true, call.Args[0], nil
+	}
+	// apart(...+...)
+	// apart(...)+apart(...)
+	if summ := parseSummArray(call.Args[0]); 1 < len(summ) {
+		var results []goast.Expr
+		for i := range summ {
+			results = append(results, callOf(apartName, summ[i].toAst(), variable))
+		}
+		r, err := s.summOfParts(results)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, r, err
+	}
+	fs, ok, err := s.partialFractions(call.Args[0], variable)
+	if err != nil {
+		return false, nil, err
+	}
+	if !ok {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "partial fractions are not found",
+		})
+	}
+	return true, fs.toAst(s, variable), nil
+}
+
+// fractions is rational function in form of partial fractions:
+//
+//	factor * (quotient + parts[0] + parts[1] + ...)
+type fractions struct {
+	factor   goast.Expr
+	quotient polynomial
+	parts    []partial
+}
+
+// partial is fraction with linear denominator `num/pow(linear,power)`,
+// where `linear` is `x-root`, or with quadratic monic denominator
+// `(num*x+free)/quad`
+type partial struct {
+	linear polynomial
+	power  int
+	quad   polynomial
+	num    goast.Expr
+	free   goast.Expr
+}
+
+// quadratic return true for partial fraction with quadratic
+// denominator
+func (p partial) quadratic() bool {
+	return 0 < len(p.quad.terms)
+}
+
+func (fs fractions) toAst(s *sm, variable goast.Expr) goast.Expr {
+	var summ goast.Expr
+	add := func(e goast.Expr) {
+		if summ == nil {
+			summ = e
+			return
+		}
+		summ = binaryOf(summ, token.ADD, e)
+	}
+	if 0 < len(fs.quotient.terms) {
+		add(s.polyToAst(fs.quotient))
+	}
+	for _, p := range fs.parts {
+		if !p.quadratic() {
+			// from : ... + -2.000/(x-1.000)
+			// to   : ... - 2.000/(x-1.000)
+			if ok, v := isRational(p.num); ok && v.Sign() < 0 && summ != nil {
+				summ = binaryOf(summ, token.SUB, binaryOf(
+					s.createRat(v.Neg(v)), token.QUO, p.denominator(s, variable)))
+				continue
+			}
+			// from : ... + -(1/a)/(a*x+1)
+			// to   : ... - (1/a)/(a*x+1)
+			if un, ok := p.num.(*goast.UnaryExpr); ok && un.Op == token.SUB && summ != nil {
+				summ = binaryOf(summ, token.SUB, binaryOf(un.X, token.QUO, p.denominator(s, variable)))
+				continue
+			}
+			add(binaryOf(p.num, token.QUO, p.denominator(s, variable)))
+			continue
+		}
+		add(binaryOf(
+			binaryOf(binaryOf(p.num, token.MUL, variable), token.ADD, p.free),
+			token.QUO,
+			p.denominator(s, variable),
+		))
+	}
+	if summ == nil {
+		summ = s.createFloat(0)
+	}
+	return s.coefficient(summ, fs.factor)
+}
+
+// denominator return denominator of partial fraction
+func (p partial) denominator(s *sm, variable goast.Expr) goast.Expr {
+	if p.quadratic() {
+		s.sortTerms(p.quad)
+		return s.polyToAst(p.quad)
+	}
+	s.sortTerms(p.linear)
+	base := s.polyToAst(p.linear)
+	if p.power == 1 {
+		return base
+	}
+	return callOf(pow, base, s.createFloat(float64(p.power)))
+}
+
+// addTerm return expression `summ+v*e` without negative number after
+// operator. Expression `e` is nil for number `v`.
+func (s *sm) addTerm(summ goast.Expr, v *big.Rat, e goast.Expr) goast.Expr {
+	// numbers are summarized exactly
+	if e != nil {
+		if ok, ev := isRational(e); ok {
+			v, e = new(big.Rat).Mul(v, ev), nil
+		}
+	}
+	if e == nil && summ != nil {
+		if ok, sv := isRational(summ); ok {
+			return s.createRat(sv.Add(sv, v))
+		}
+	}
+	term := func(v *big.Rat) goast.Expr {
+		if e == nil {
+			return s.createRat(v)
+		}
+		if v.Cmp(big.NewRat(1, 1)) == 0 {
+			return e
+		}
+		return binaryOf(s.createRat(v), token.MUL, e)
+	}
+	switch {
+	case summ == nil:
+		return term(v)
+	case v.Sign() < 0:
+		return binaryOf(summ, token.SUB, term(new(big.Rat).Neg(v)))
+	}
+	return binaryOf(summ, token.ADD, term(v))
+}
+
+// integrate return antiderivative of partial fraction
+//
+//	integral(a/(x-r), x)           = a*log(abs(x-r))
+//	integral(a/pow(x-r,n), x)      = a*pow(x-r,1-n)/(1-n)
+//	integral((a*x+b)/(x*x+p*x+q))  = a/2*log(x*x+p*x+q) + (b-a*p/2)*integral(1/(x*x+p*x+q))
+func (p partial) integrate(s *sm, variable goast.Expr) goast.Expr {
+	if !p.quadratic() {
+		s.sortTerms(p.linear)
+		base := s.polyToAst(p.linear)
+		if p.power == 1 {
+			return binaryOf(p.num, token.MUL, callOf(logName, callOf(absName, base)))
+		}
+		exp := s.createFloat(float64(1 - p.power))
+		return binaryOf(
+			binaryOf(p.num, token.MUL, callOf(pow, base, exp)),
+			token.QUO,
+			exp,
+		)
+	}
+	name := astToStr(variable)
+	linear, _ := p.quad.coefficientOf(name, 1).evaluate(nil)
+	free, _ := p.quad.coefficientOf(name, 0).evaluate(nil)
+	half := new(big.Rat).Quo(linear, big.NewRat(2, 1))
+	shift := s.addTerm(variable, half, nil)
+	rest := s.addTerm(p.free, new(big.Rat).Neg(half), p.num)
+
+	// discriminant of shifted form `(x+p/2)*(x+p/2) + disc`
+	disc := new(big.Rat).Sub(free, new(big.Rat).Mul(half, half))
+	denominator := p.denominator(s, variable)
+	if disc.Sign() <= 0 {
+		// denominator with real roots is negative between roots
+		denominator = callOf(absName, denominator)
+	}
+	logarithm := binaryOf(
+		binaryOf(p.num, token.QUO, s.createFloat(2)),
+		token.MUL,
+		callOf(logName, denominator),
+	)
+	if 0 < disc.Sign() {
+		// integral(1/((x+p/2)*(x+p/2)+k*k), x) = atan((x+p/2)/k)/k
+		k := s.sqrtRat(disc)
+		return binaryOf(logarithm, token.ADD, binaryOf(
+			rest,
+			token.MUL,
+			binaryOf(callOf(atanName, binaryOf(shift, token.QUO, k)), token.QUO, k),
+		))
+	}
+	// integral(1/((x+p/2)*(x+p/2)-k*k), x) = log(abs((x+p/2-k)/(x+p/2+k)))/(2*k)
+	k := s.sqrtRat(disc.Neg(disc))
+	return binaryOf(logarithm, token.ADD, binaryOf(
+		rest,
+		token.MUL,
+		binaryOf(
+			callOf(logName, callOf(absName, binaryOf(
+				binaryOf(shift, token.SUB, k),
+				token.QUO,
+				binaryOf(shift, token.ADD, k),
+			))),
+			token.QUO,
+			binaryOf(s.createFloat(2), token.MUL, k),
+		),
+	))
+}
+
+// sqrtRat return square root of positive rational value. Result is
+// exact for squares of rational values.
+func (s *sm) sqrtRat(v *big.Rat) goast.Expr {
+	num, den := new(big.Int).Sqrt(v.Num()), new(big.Int).Sqrt(v.Denom())
+	if new(big.Int).Mul(num, num).Cmp(v.Num()) == 0 &&
+		new(big.Int).Mul(den, den).Cmp(v.Denom()) == 0 {
+		return s.createRat(new(big.Rat).SetFrac(num, den))
+	}
+	return callOf(sqrtName, s.createRat(v))
+}
+
+// integrateRational return antiderivative of rational function by
+// partial fractions
+func (s *sm) integrateRational(function, variable goast.Expr) (r goast.Expr, ok bool, err error) {
+	fs, ok, err := s.partialFractions(function, variable)
+	if err != nil || !ok || len(fs.parts) == 0 {
+		return nil, false, err
+	}
+	summ := goast.Expr(nil)
+	add := func(e goast.Expr) {
+		if summ == nil {
+			summ = e
+			return
+		}
+		summ = binaryOf(summ, token.ADD, e)
+	}
+	if 0 < len(fs.quotient.terms) {
+		add(callOf(integralName, s.polyToAst(fs.quotient), variable))
+	}
+	for _, p := range fs.parts {
+		add(p.integrate(s, variable))
+	}
+	return s.coefficient(summ, fs.factor), true, nil
+}
+
+// partialFractions return partial fraction decomposition of rational
+// function by variable. Numerator may have coefficients independent of
+// variable. Denominator with numeric coefficients must have rational
+// roots, except of quadratic factor. Denominator with symbolic
+// coefficients must have monomial leading coefficient and different
+// linear factors.
+func (s *sm) partialFractions(e, variable goast.Expr) (fs fractions, ok bool, err error) {
+	name := astToStr(variable)
+
+	// factors independent of variable
+	var up, do, factor quoArray
+	q := s.parseQuoArray(e)
+	for i := range q.up {
+		if s.independent(q.up[i], name) {
+			factor.up = append(factor.up, q.up[i])
+		} else {
+			up.up = append(up.up, q.up[i])
+		}
+	}
+	for i := range q.do {
+		if s.independent(q.do[i], name) {
+			factor.do = append(factor.do, q.do[i])
+		} else {
+			do.up = append(do.up, q.do[i])
+		}
+	}
+	if len(do.up) == 0 {
+		return fs, false, nil
+	}
+
+	num, ok, err := s.polyOf(s.quoToAst(up), name)
+	if err != nil || !ok {
+		return fs, false, err
+	}
+	den, ok, err := s.polyOf(s.quoToAst(do), name)
+	if err != nil || !ok {
+		return fs, false, err
+	}
+	degree := den.degreeOf(name)
+	if degree < 1 {
+		return fs, false, nil
+	}
+	if 1 < len(den.vars()) {
+		fs.factor = s.quoToAst(factor)
+		return s.linearFractions(fs, num, den, name)
+	}
+
+	// monic denominator
+	lead, _ := den.coefficientOf(name, degree).evaluate(nil)
+	den = den.divide(monomial{coeff: lead, powers: map[string]int{}})
+	if lead.Cmp(big.NewRat(1, 1)) != 0 {
+		factor.do = append(factor.do, s.createRat(lead))
+	}
+	fs.factor = s.quoToAst(factor)
+
+	// polynomial division
+	fs.quotient, num = num.quoRem(den, name)
+	s.sortTerms(fs.quotient)
+
+	// factorization of denominator
+	roots, rest := den.roots(name)
+	switch rest.degreeOf(name) {
+	case 0:
+	case 2:
+		lead, _ := rest.coefficientOf(name, 2).evaluate(nil)
+		rest = rest.divide(monomial{coeff: lead, powers: map[string]int{}})
+	default:
+		return fs, false, nil
+	}
+
+	// polynomials of unknown coefficients
+	var basis []polynomial
+	for _, root := range roots {
+		poly := den
+		for p := 1; p <= root.power; p++ {
+			poly, _ = poly.quo(linearPoly(den, name, root.value))
+			fs.parts = append(fs.parts, partial{linear: linearPoly(den, name, root.value), power: p})
+			basis = append(basis, poly)
+		}
+	}
+	if rest.degreeOf(name) == 2 {
+		poly, _ := den.quo(rest)
+		fs.parts = append(fs.parts, partial{quad: rest})
+		basis = append(basis, poly.times(monomial{
+			coeff:  big.NewRat(1, 1),
+			powers: map[string]int{name: 1},
+		}), poly)
+	}
+
+	// system of linear equations for coefficients
+	m := make([][]*big.Rat, degree)
+	for i := range m {
+		m[i] = make([]*big.Rat, degree)
+		for j := range basis {
+			m[i][j], _ = basis[j].coefficientOf(name, i).evaluate(nil)
+		}
+	}
+	inv, ok := inverseRat(m)
+	if !ok {
+		return fs, false, nil
+	}
+	unknowns := make([]goast.Expr, degree)
+	for i := range unknowns {
+		for j := 0; j < degree; j++ {
+			c := num.coefficientOf(name, j)
+			if inv[i][j].Sign() == 0 || len(c.terms) == 0 {
+				continue
+			}
+			s.sortTerms(c)
+			unknowns[i] = s.addTerm(unknowns[i], inv[i][j], s.polyToAst(c))
+		}
+		if unknowns[i] == nil {
+			unknowns[i] = s.createFloat(0)
+		}
+	}
+	for i := range fs.parts {
+		fs.parts[i].num = unknowns[i]
+	}
+	if rest.degreeOf(name) == 2 {
+		fs.parts[len(fs.parts)-1].free = unknowns[degree-1]
+	}
+	return fs, true, nil
+}
+
+// linearFractions return partial fractions of rational function with
+// symbolic coefficients of denominator
+//
+//	x/(a*x+1) = 1/a - 1/(a*a)/(x+1/a)
+func (s *sm) linearFractions(fs fractions, num, den polynomial, name string) (_ fractions, ok bool, _ error) {
+	if len(den.coefficientOf(name, den.degreeOf(name)).terms) != 1 {
+		return fs, false, nil
+	}
+	fs.quotient, num = num.quoRem(den, name)
+	s.sortTerms(fs.quotient)
+	factors, rest := den.linearFactors(name)
+	if 0 < rest.degreeOf(name) {
+		return fs, false, nil
+	}
+	for i, f := range factors {
+		for _, g := range factors[:i] {
+			if len(f.sub(g).terms) == 0 {
+				// multiple factor
+				return fs, false, nil
+			}
+		}
+	}
+	for _, f := range factors {
+		u := f.coefficientOf(name, 1)
+		if len(u.terms) != 1 {
+			return fs, false, nil
+		}
+		// root `v/u` of factor `u*x - v`
+		root := f.coefficientOf(name, 0).times(monomial{
+			coeff:  big.NewRat(-1, 1),
+			powers: map[string]int{},
+		}).times(u.terms[0].inverse())
+
+		// coefficient num(root)/(u*other(root)) of fraction with
+		// denominator `x - root`, where `other` is product of other
+		// factors
+		other, _ := den.quo(f)
+		value := other.at(name, root).times(u.terms[0])
+		if len(value.terms) == 0 {
+			return fs, false, nil
+		}
+		c := num.at(name, root)
+		if len(c.terms) == 0 {
+			continue
+		}
+		p := partial{linear: f.times(u.terms[0].inverse()), power: 1}
+		if len(value.terms) == 1 {
+			c = c.times(value.terms[0].inverse())
+			s.sortTerms(c)
+			p.num = s.polyToAst(c)
+		} else {
+			// from : -1/(-a+b)
+			// to   : 1/(a-b)
+			negative := monomial{coeff: big.NewRat(-1, 1), powers: map[string]int{}}
+			s.sortTerms(value)
+			if value.terms[0].coeff.Sign() < 0 {
+				c, value = c.times(negative), value.times(negative)
+			}
+			s.sortTerms(c)
+			if c.terms[0].coeff.Sign() < 0 {
+				p.num = &goast.UnaryExpr{Op: token.SUB, X: paren(binaryOf(
+					s.polyToAst(c.times(negative)), token.QUO, s.polyToAst(value)))}
+			} else {
+				p.num = binaryOf(s.polyToAst(c), token.QUO, s.polyToAst(value))
+			}
+		}
+		fs.parts = append(fs.parts, p)
+	}
+	return fs, true, nil
+}
+
+// polyOf return polynomial of expanded expression by atom. Result is not
+// ok for negative powers of atom or coefficients dependent on atom.
+func (s *sm) polyOf(e goast.Expr, name string) (p polynomial, ok bool, err error) {
+	e, err = s.simplify(e)
+	if err != nil {
+		return p, false, err
+	}
+	p = s.poly(e)
+	for key, atom := range p.atoms {
+		if key != name && !s.independent(atom, name) {
+			return p, false, nil
+		}
+	}
+	for _, m := range p.terms {
+		if m.powers[name] < 0 {
+			return p, false, nil
+		}
+	}
+	return p, true, nil
+}
+
+// root of polynomial with multiplicity
+type root struct {
+	value *big.Rat
+	power int
+}
+
+// roots return rational roots of polynomial with numeric coefficients by
+// atom and rest polynomial without rational roots
+func (p polynomial) roots(name string) (roots []root, rest polynomial) {
+	factors, rest := p.linearFactors(name)
+next:
+	for _, f := range factors {
+		// from : u*x - v
+		// to   : v/u
+		u, _ := f.coefficientOf(name, 1).evaluate(nil)
+		v, _ := f.coefficientOf(name, 0).evaluate(nil)
+		value := v.Quo(v, u)
+		value.Neg(value)
+		for i := range roots {
+			if roots[i].value.Cmp(value) == 0 {
+				roots[i].power++
+				continue next
+			}
+		}
+		roots = append(roots, root{value: value, power: 1})
+	}
+	return
+}
+
+// linearPoly return polynomial `x-root` with atoms of polynomial
+func linearPoly(p polynomial, name string, root *big.Rat) (r polynomial) {
+	r.atoms = p.atoms
+	r.add(monomial{coeff: big.NewRat(1, 1), powers: map[string]int{name: 1}})
+	r.add(monomial{coeff: new(big.Rat).Neg(root), powers: map[string]int{}})
+	return
+}
+
+// inverseRat return inverse of square matrix by Gauss-Jordan elimination
+func inverseRat(m [][]*big.Rat) (inv [][]*big.Rat, ok bool) {
+	n := len(m)
+	a := make([][]*big.Rat, n)
+	inv = make([][]*big.Rat, n)
+	for i := range m {
+		a[i] = make([]*big.Rat, n)
+		inv[i] = make([]*big.Rat, n)
+		for j := range m[i] {
+			a[i][j] = new(big.Rat).Set(m[i][j])
+			inv[i][j] = new(big.Rat)
+		}
+		inv[i][i].SetInt64(1)
+	}
+	for c := 0; c < n; c++ {
+		pivot := -1
+		for r := c; r < n; r++ {
+			if a[r][c].Sign() != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, false
+		}
+		a[c], a[pivot] = a[pivot], a[c]
+		inv[c], inv[pivot] = inv[pivot], inv[c]
+		p := new(big.Rat).Set(a[c][c])
+		for j := 0; j < n; j++ {
+			a[c][j].Quo(a[c][j], p)
+			inv[c][j].Quo(inv[c][j], p)
+		}
+		for r := 0; r < n; r++ {
+			if r == c || a[r][c].Sign() == 0 {
+				continue
+			}
+			f := new(big.Rat).Set(a[r][c])
+			for j := 0; j < n; j++ {
+				a[r][j].Sub(a[r][j], new(big.Rat).Mul(f, a[c][j]))
+				inv[r][j].Sub(inv[r][j], new(big.Rat).Mul(f, inv[c][j]))
+			}
+		}
+	}
+	return inv, true
+}
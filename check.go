@@ -0,0 +1,110 @@
+package sm
+
+import (
+	"go/token"
+	"math"
+	"math/rand"
+	"strconv"
+
+	goast "go/ast"
+)
+
+// CheckEquivalent check numerically that simplified expression `out`
+// is same as expression `expr`. Both expressions are calculated for
+// few random values of constants and variables in range [0.5, 1.5).
+// Expression `expr` is calculated numerically without simplification,
+// except parts with derivatives, integrals and matrix operations. Values
+// of constants are injected in these parts before simplification,
+// values of variables are injected after simplification. Relative
+// difference of values must be less then `tol`, values of `expr` out of
+// domain or range of float64 are not compared. Random values are same
+// for each call. If expressions are not same, then return error
+// *EquivalenceError. If expressions cannot be calculated, for example
+// with not defined function, then return other error.
+// Example:
+//
+//	expr : "d(a*pow(x,3),x); constant(a); variable(x)"
+//	out  : "3.000*(a*(x*x))"
+func CheckEquivalent(expr, out string, tol float64) error {
+	var s sm
+	if err := s.parse(expr); err != nil {
+		return err
+	}
+	a, err := parseExpr(s.base)
+	if err != nil {
+		return s.errorGen(err)
+	}
+	o, err := parseExpr(out)
+	if err != nil {
+		return s.errorGen(err)
+	}
+
+	names := s.params(a)
+	isVariable := map[string]bool{}
+	for _, v := range s.vars {
+		isVariable[v] = true
+	}
+
+	const points = 3
+	rnd := rand.New(rand.NewSource(1))
+	for point := 0; point < points; point++ {
+		bindings := map[string]float64{}
+		constants := map[string]goast.Expr{}
+		for _, name := range names {
+			v := 0.5 + rnd.Float64()
+			bindings[external(name)] = v
+			if !isVariable[name] {
+				constants[name] = &goast.BasicLit{
+					Kind:  token.FLOAT,
+					Value: strconv.FormatFloat(v, 'g', -1, 64),
+				}
+			}
+		}
+
+		// input expression is calculated numerically, only parts with
+		// derivatives, integrals and matrix operations are simplified
+		c := s.copy()
+		c.evalOptions()
+		c.base = astToStr(a)
+		e, err := c.reduce(substitute(a, constants), bindings)
+		if err != nil {
+			return err
+		}
+		expect, err := c.evaluateAll(e, bindings)
+		if err != nil {
+			return err
+		}
+
+		// simplified expression
+		actual, err := s.evaluateAll(o, bindings)
+		if err != nil {
+			return err
+		}
+
+		if len(expect) != len(actual) {
+			return s.errorGen(&EquivalenceError{
+				Bindings: bindings,
+				Msg:      "not same amount of values",
+			})
+		}
+		for i := range expect {
+			if math.IsNaN(expect[i]) || math.IsInf(expect[i], 0) {
+				// outside of domain, for example `sqrt(-1)`, or
+				// overflow, for example `pow(x,100000000)`
+				continue
+			}
+			diff := math.Abs(expect[i] - actual[i])
+			scale := math.Max(1, math.Max(math.Abs(expect[i]), math.Abs(actual[i])))
+			if tol*scale < diff || math.IsNaN(diff) {
+				return s.errorGen(&EquivalenceError{
+					Bindings: bindings,
+					Index:    i,
+					Expect:   expect[i],
+					Actual:   actual[i],
+					Msg:      "not same values",
+				})
+			}
+		}
+	}
+	return nil
+}
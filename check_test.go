@@ -0,0 +1,50 @@
+package sm
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCheckEquivalent(t *testing.T) {
+	for i, tc := range []struct {
+		expr  string
+		out   string
+		equal bool
+	}{
+		{"d(a*pow(x,3),x); constant(a); variable(x)", "3.000*(a*(x*x))", true},
+		{"d(a*pow(x,3),x); constant(a); variable(x)", "3.000*(a*x)", false},
+		{"integral(x*x,x,0,L); variable(x)", "0.333*(L*(L*L))", true},
+		{"integral(x*x,x,0,L); variable(x)", "0.5*(L*L)", false},
+		{"transpose(matrix(a,b,1,2,2,2))", "matrix(a,1,b,2,2,2)", true},
+		{"transpose(matrix(a,b,1,2,2,2))", "matrix(a,b,1,2,2,2)", false},
+		{"matrix(a,b,1,2)", "a", false},
+		{"integral(x*x*x,x,0,2,gauss2);variable(x)", "4", true},
+		{"integral2(x*y,x,0,1,y,0,a,gauss2);variable(x);variable(y)", "0.25*a*a", true},
+		{"integral2(x,x,0,1,y,0,1-x,triangle3);variable(x);variable(y)", "0.5", false},
+		{"pow(a+b,2)*x; variable(x)", "(a*a+2*a*b+b*b)*x", true},
+		{"pow(a+b,2)*x; variable(x)", "(a*a+b*b)*x", false},
+		{"sin(x)*sin(x)+cos(x)*cos(x); variable(x)", "1", true},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			err := CheckEquivalent(tc.expr, tc.out, 1e-2)
+			if tc.equal {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var ee *EquivalenceError
+			if !errors.As(err, &ee) {
+				t.Fatalf("not valid error: %v", err)
+			}
+		})
+	}
+	t.Run("function", func(t *testing.T) {
+		err := CheckEquivalent("d(u,x);function(u,x)", "d(u,x)", 1e-2)
+		var ee *EquivalenceError
+		if err == nil || errors.As(err, &ee) {
+			t.Fatalf("not valid error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package sm
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	goast "go/ast"
+)
+
+// GoFunc return source code of Go function with name `name` for simplified
+// expression. Constants and variables of expression are parameters of
+// function. Matrix is returned as `[][]float64`, other expressions as
+// `float64`. Repeated sub-expressions are calculated once. Primes of
+// names are replaced by `Prime`, for example `u'` is `uPrime`.
+// Example:
+//
+//	expr : "a*pow(x,2) + sin(a*pow(x,2)); constant(a); variable(x)"
+//	out  :
+//
+//	// f is generated function of expression: a*pow(x,2) + sin(a*pow(x,2))
+//	func f(a, x float64) float64 {
+//		t1 := a * (x * x)
+//		return t1 + math.Sin(t1)
+//	}
+func GoFunc(name, expr string) (code string, err error) {
+	var s sm
+	if err = s.parse(expr); err != nil {
+		return
+	}
+	a, err := parser.ParseExpr(s.base)
+	if err != nil {
+		return "", s.errorGen(err)
+	}
+
+	// arguments of function
+	params := s.params(a)
+
+	// body of function
+	var (
+		results []goast.Expr
+		mt      *matriX
+	)
+	mt, ok, err := isMatrix(a)
+	if err != nil {
+		return "", s.errorGen(err)
+	}
+	if ok {
+		results = mt.Args
+	} else {
+		results = []goast.Expr{a}
+	}
+	names := map[string]goast.Expr{}
+	for i := range params {
+		if name := goName(params[i]); name != params[i] {
+			names[params[i]] = goast.NewIdent(name)
+			params[i] = name
+		}
+	}
+	for i := range results {
+		results[i], err = s.goExpr(results[i])
+		if err != nil {
+			return "", err
+		}
+		results[i] = goSubstitute(results[i], names)
+	}
+	defs, results := cse(results, "t", params)
+
+	// expression as in input
+	header := s.base
+	if 0 <= s.segment {
+		header = s.segments[s.segment]
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// %s is generated function of expression: %s\n",
+		name, strings.TrimSpace(header))
+	result := "float64"
+	if mt != nil {
+		result = "[][]float64"
+	}
+	if len(params) == 0 {
+		fmt.Fprintf(&buf, "func %s() %s {\n", name, result)
+	} else {
+		fmt.Fprintf(&buf, "func %s(%s float64) %s {\n",
+			name, strings.Join(params, ", "), result)
+	}
+	for _, d := range defs {
+		fmt.Fprintf(&buf, "%s := %s\n", d.name, goCode(d.value))
+	}
+	if mt == nil {
+		fmt.Fprintf(&buf, "return %s\n", goCode(results[0]))
+	} else {
+		fmt.Fprintf(&buf, "return [][]float64{\n")
+		for r := 0; r < mt.Rows; r++ {
+			var row []string
+			for c := 0; c < mt.Cols; c++ {
+				row = append(row, goCode(results[mt.position(r, c)]))
+			}
+			fmt.Fprintf(&buf, "{%s},\n", strings.Join(row, ", "))
+		}
+		fmt.Fprintf(&buf, "}\n")
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", s.errorGen(err)
+	}
+	return string(src), nil
+}
+
+// params return names of constants and variables of expression. Names are
+// in order of declaration, not declared names are sorted.
+func (s sm) params(a goast.Expr) (params []string) {
+	used := map[string]bool{}
+	var inspect func(n goast.Node) bool
+	inspect = func(n goast.Node) bool {
+		if call, ok := n.(*goast.CallExpr); ok {
+			// ignore name of function and name of quadrature scheme
+			scheme, ok := schemeArg(call)
+			for i := range call.Args {
+				if ok && i == scheme {
+					continue
+				}
+				goast.Inspect(call.Args[i], inspect)
+			}
+			return false
+		}
+		if id, ok := n.(*goast.Ident); ok {
+			used[id.Name] = true
+		}
+		return true
+	}
+	goast.Inspect(a, inspect)
+	for _, list := range [][]string{s.cons, s.vars} {
+		for _, name := range list {
+			if used[name] {
+				params = append(params, name)
+				delete(used, name)
+			}
+		}
+	}
+	var others []string
+	for name := range used {
+		others = append(others, name)
+	}
+	sort.Strings(others)
+	return append(params, others...)
+}
+
+// goExpr convert expression to Go expression
+func (s sm) goExpr(e goast.Expr) (r goast.Expr, err error) {
+	switch v := e.(type) {
+	case *goast.ParenExpr:
+		return s.goExpr(v.X)
+
+	case *goast.BasicLit:
+		if v.Kind == token.INT {
+			// avoid integer division
+			return &goast.BasicLit{Kind: token.FLOAT, Value: v.Value + ".0"}, nil
+		}
+		return v, nil
+
+	case *goast.Ident:
+		if v.Name == "math" {
+			return nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: v.Name},
+				Msg:      "name is same as package name",
+			})
+		}
+		return v, nil
+
+	case *goast.UnaryExpr:
+		x, err := s.goExpr(v.X)
+		if err != nil {
+			return nil, err
+		}
+		return &goast.UnaryExpr{Op: v.Op, X: x}, nil
+
+	case *goast.BinaryExpr:
+		x, err := s.goExpr(v.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := s.goExpr(v.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &goast.BinaryExpr{X: x, Op: v.Op, Y: y}, nil
+
+	case *goast.CallExpr:
+		id, ok := v.Fun.(*goast.Ident)
+		if !ok {
+			break
+		}
+		var args []goast.Expr
+		for i := range v.Args {
+			arg, err := s.goExpr(v.Args[i])
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		switch {
+		case id.Name == pow && len(args) == 2:
+			return goPow(args[0], args[1]), nil
+		case isElementary(id.Name) && len(args) == 1:
+			return goMath(strings.ToUpper(id.Name[:1])+id.Name[1:], args...), nil
+		}
+	}
+	return nil, s.errorGen(&UnsupportedError{
+		Location: Location{Expr: astToStr(e)},
+		Msg:      "cannot generate Go code",
+	})
+}
+
+// goName return Go name of constant or variable, primes are replaced by
+// `Prime`, for example `u'` is `uPrime`
+func goName(name string) string {
+	return strings.Replace(name, prime, "Prime", -1)
+}
+
+// goMath return call of function from package `math`
+func goMath(name string, args ...goast.Expr) goast.Expr {
+	return &goast.CallExpr{
+		Fun: &goast.SelectorExpr{
+			X:   goast.NewIdent("math"),
+			Sel: goast.NewIdent(name),
+		},
+		Args: args,
+	}
+}
+
+// goPow return power of base. Power with small integer exponent is
+// repeated multiplication, other powers are calculated by `math.Pow`.
+func goPow(base, exponent goast.Expr) goast.Expr {
+	ok, n := isNumber(exponent)
+	if !ok || n != float64(int64(n)) || 4 < n || n < -4 {
+		return goMath("Pow", base, exponent)
+	}
+	exn := int64(n)
+	if exn == 0 {
+		return &goast.BasicLit{Kind: token.FLOAT, Value: "1.0"}
+	}
+	negative := exn < 0
+	if negative {
+		exn = -exn
+	}
+	r := base
+	for i := int64(1); i < exn; i++ {
+		r = &goast.BinaryExpr{X: r, Op: token.MUL, Y: base}
+	}
+	if negative {
+		r = &goast.BinaryExpr{
+			X:  &goast.BasicLit{Kind: token.FLOAT, Value: "1.0"},
+			Op: token.QUO,
+			Y:  r,
+		}
+	}
+	return r
+}
+
+// goPrecedence return precedence of Go expression
+func goPrecedence(e goast.Expr) int {
+	switch v := e.(type) {
+	case *goast.BinaryExpr:
+		return v.Op.Precedence()
+	case *goast.UnaryExpr:
+		return token.UnaryPrec
+	}
+	return token.HighestPrec
+}
+
+// goCode return Go source code of expression with minimal amount of parens
+func goCode(e goast.Expr) string {
+	switch v := e.(type) {
+	case *goast.UnaryExpr:
+		x := goCode(v.X)
+		if goPrecedence(v.X) <= token.UnaryPrec {
+			x = "(" + x + ")"
+		}
+		return v.Op.String() + x
+
+	case *goast.BinaryExpr:
+		prec := v.Op.Precedence()
+		x, y := goCode(v.X), goCode(v.Y)
+		if goPrecedence(v.X) < prec {
+			x = "(" + x + ")"
+		}
+		if goPrecedence(v.Y) <= prec {
+			y = "(" + y + ")"
+		}
+		return x + " " + v.Op.String() + " " + y
+
+	case *goast.CallExpr:
+		var args []string
+		for i := range v.Args {
+			args = append(args, goCode(v.Args[i]))
+		}
+		return astToStr(v.Fun) + "(" + strings.Join(args, ", ") + ")"
+	}
+	return astToStr(e)
+}
@@ -0,0 +1,100 @@
+package sm
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestGoFunc(t *testing.T) {
+	for i, tc := range []struct {
+		expr string
+		code string
+	}{
+		{
+			expr: "a*pow(x,2) + sin(a*pow(x,2)); constant(a); variable(x)",
+			code: `// f is generated function of expression: a*pow(x,2) + sin(a*pow(x,2))
+func f(a, x float64) float64 {
+	t1 := a * (x * x)
+	return t1 + math.Sin(t1)
+}
+`,
+		},
+		{
+			expr: "matrix(a*b, -a*b, 1/3, pow(x, 2.5)-(a-b), 2, 2);variable(x);constant(b)",
+			code: `// f is generated function of expression: matrix(a*b, -a*b, 1/3, pow(x, 2.5)-(a-b), 2, 2)
+func f(b, x, a float64) [][]float64 {
+	return [][]float64{
+		{a * b, -a * b},
+		{1.0 / 3.0, math.Pow(x, 2.5) - (a - b)},
+	}
+}
+`,
+		},
+		{
+			expr: "t1*(a+b)+cos(a+b)*(a+b)/pow(a+b,-2)",
+			code: `// f is generated function of expression: t1*(a+b)+cos(a+b)*(a+b)/pow(a+b,-2)
+func f(a, b, t1 float64) float64 {
+	t2 := a + b
+	return t1*t2 + math.Cos(t2)*t2/(1.0/(t2*t2))
+}
+`,
+		},
+		{
+			expr: "exp(a*x)+sqrt(x)*atan(a*x);variable(x)",
+			code: `// f is generated function of expression: exp(a*x)+sqrt(x)*atan(a*x)
+func f(x, a float64) float64 {
+	t1 := a * x
+	return math.Exp(t1) + math.Sqrt(x)*math.Atan(t1)
+}
+`,
+		},
+		{
+			expr: "u'*x + sin(u'*x)",
+			code: `// f is generated function of expression: u'*x + sin(u'*x)
+func f(uPrime, x float64) float64 {
+	t1 := uPrime * x
+	return t1 + math.Sin(t1)
+}
+`,
+		},
+		{
+			expr: "2",
+			code: `// f is generated function of expression: 2
+func f() float64 {
+	return 2.0
+}
+`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			code, err := GoFunc("f", tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tc.code {
+				t.Fatalf("Is not same \nActual :\n%s\nExpect :\n%s", code, tc.code)
+			}
+
+			// check compilation
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "", "package p\nimport \"math\"\nvar _ = math.Pi\n"+code, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+			if _, err = conf.Check("p", fset, []*ast.File{file}, nil); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	t.Run("Unsupported", func(t *testing.T) {
+		if _, err := GoFunc("f", "d(u,x);function(u,x)"); err == nil {
+			t.Fatalf("error is not found")
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package sm
+
+import (
+	"go/parser"
+	"strconv"
+	"strings"
+
+	goast "go/ast"
+)
+
+// Temp is temporary variable of common subexpression elimination.
+type Temp struct {
+	Name string
+	Expr string
+}
+
+// CSE is common subexpression elimination of expression. Repeated
+// sub-expressions are replaced by temporary variables `t1`, `t2`, ...
+// Temporary variables are in order of calculation.
+// Example:
+//
+//	expr   : "a*pow(x,2) + sin(a*pow(x,2)); constant(a); variable(x)"
+//	temps  : t1 = a * pow(x, 2)
+//	result : t1 + sin(t1)
+func CSE(expr string) (temps []Temp, result string, err error) {
+	var s sm
+	if err = s.parse(expr); err != nil {
+		return
+	}
+	a, err := parser.ParseExpr(s.base)
+	if err != nil {
+		return nil, "", s.errorGen(err)
+	}
+	mt, ok, err := isMatrix(a)
+	if err != nil {
+		return nil, "", s.errorGen(err)
+	}
+	es := []goast.Expr{a}
+	if ok {
+		es = mt.Args
+	}
+	defs, rs := cse(es, "t", s.params(a))
+	for _, d := range defs {
+		temps = append(temps, Temp{Name: d.name, Expr: external(goCode(d.value))})
+	}
+	if !ok {
+		return temps, external(goCode(rs[0])), nil
+	}
+	var args []string
+	for i := range rs {
+		args = append(args, goCode(rs[i]))
+	}
+	args = append(args, strconv.Itoa(mt.Rows), strconv.Itoa(mt.Cols))
+	return temps, external(matrix + "(" + strings.Join(args, ", ") + ")"), nil
+}
+
+type cseDef struct {
+	name  string
+	value goast.Expr
+}
+
+// cse is common subexpression elimination. Repeated sub-expressions of
+// expressions are replaced by temporary variables with prefix `prefix`.
+// Names of temporary variables are not same as names in `used`.
+func cse(es []goast.Expr, prefix string, used []string) (defs []cseDef, rs []goast.Expr) {
+	// remove parens
+	for i := range es {
+		es[i] = goSubstitute(es[i], nil)
+	}
+
+	// amount of sub-expressions
+	counter := map[string]int{}
+	var count func(e goast.Expr)
+	count = func(e goast.Expr) {
+		switch v := e.(type) {
+		case *goast.BinaryExpr:
+			count(v.X)
+			count(v.Y)
+		case *goast.UnaryExpr:
+			count(v.X)
+			return
+		case *goast.CallExpr:
+			for i := range v.Args {
+				count(v.Args[i])
+			}
+		default:
+			return
+		}
+		counter[goCode(e)]++
+	}
+	for i := range es {
+		count(es[i])
+	}
+
+	// names of temporary variables
+	names := map[string]bool{}
+	for _, name := range used {
+		names[name] = true
+	}
+	index := 1
+	newName := func() string {
+		for {
+			name := prefix + strconv.Itoa(index)
+			index++
+			if !names[name] {
+				return name
+			}
+		}
+	}
+
+	// replace sub-expressions from bottom to top
+	vars := map[string]string{}
+	var replace func(e goast.Expr) goast.Expr
+	replace = func(e goast.Expr) goast.Expr {
+		var r goast.Expr
+		switch v := e.(type) {
+		case *goast.BinaryExpr:
+			r = &goast.BinaryExpr{X: replace(v.X), Op: v.Op, Y: replace(v.Y)}
+		case *goast.UnaryExpr:
+			return &goast.UnaryExpr{Op: v.Op, X: replace(v.X)}
+		case *goast.CallExpr:
+			call := &goast.CallExpr{Fun: v.Fun}
+			for i := range v.Args {
+				call.Args = append(call.Args, replace(v.Args[i]))
+			}
+			r = call
+		default:
+			return e
+		}
+		key := goCode(e)
+		if counter[key] < 2 {
+			return r
+		}
+		if name, ok := vars[key]; ok {
+			return goast.NewIdent(name)
+		}
+		name := newName()
+		vars[key] = name
+		defs = append(defs, cseDef{name: name, value: r})
+		return goast.NewIdent(name)
+	}
+	for i := range es {
+		rs = append(rs, replace(es[i]))
+	}
+
+	// inline temporary variables used once
+	uses := map[string]int{}
+	for _, e := range append(exprsOf(defs), rs...) {
+		goast.Inspect(e, func(n goast.Node) bool {
+			if id, ok := n.(*goast.Ident); ok {
+				uses[id.Name]++
+			}
+			return true
+		})
+	}
+	inline := map[string]goast.Expr{}
+	var rename []cseDef
+	for _, d := range defs {
+		d.value = goSubstitute(d.value, inline)
+		if uses[d.name] < 2 {
+			inline[d.name] = d.value
+			continue
+		}
+		rename = append(rename, d)
+	}
+
+	// names of temporary variables without gaps
+	index = 1
+	names = map[string]bool{}
+	for _, name := range used {
+		names[name] = true
+	}
+	newNames := map[string]goast.Expr{}
+	for i := range rename {
+		newNames[rename[i].name] = goast.NewIdent(newName())
+	}
+	defs = nil
+	for _, d := range rename {
+		defs = append(defs, cseDef{
+			name:  newNames[d.name].(*goast.Ident).Name,
+			value: goSubstitute(d.value, newNames),
+		})
+	}
+	for i := range rs {
+		rs[i] = goSubstitute(goSubstitute(rs[i], inline), newNames)
+	}
+	return
+}
+
+func exprsOf(defs []cseDef) (es []goast.Expr) {
+	for i := range defs {
+		es = append(es, defs[i].value)
+	}
+	return
+}
+
+// goSubstitute return expression without parens and with identifiers
+// replaced by expressions
+func goSubstitute(e goast.Expr, m map[string]goast.Expr) goast.Expr {
+	switch v := e.(type) {
+	case *goast.Ident:
+		if r, ok := m[v.Name]; ok {
+			return r
+		}
+	case *goast.ParenExpr:
+		return goSubstitute(v.X, m)
+	case *goast.BinaryExpr:
+		return &goast.BinaryExpr{
+			X:  goSubstitute(v.X, m),
+			Op: v.Op,
+			Y:  goSubstitute(v.Y, m),
+		}
+	case *goast.UnaryExpr:
+		return &goast.UnaryExpr{Op: v.Op, X: goSubstitute(v.X, m)}
+	case *goast.CallExpr:
+		call := &goast.CallExpr{Fun: v.Fun}
+		for i := range v.Args {
+			call.Args = append(call.Args, goSubstitute(v.Args[i], m))
+		}
+		return call
+	}
+	return e
+}
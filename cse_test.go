@@ -0,0 +1,58 @@
+package sm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCSE(t *testing.T) {
+	for i, tc := range []struct {
+		expr   string
+		temps  []Temp
+		result string
+	}{
+		{
+			expr:   "a*pow(x,2) + sin(a*pow(x,2)); constant(a); variable(x)",
+			temps:  []Temp{{"t1", "a * pow(x, 2)"}},
+			result: "t1 + sin(t1)",
+		},
+		{
+			expr:   "u'*x + sin(u'*x)",
+			temps:  []Temp{{"t1", "u' * x"}},
+			result: "t1 + sin(t1)",
+		},
+		{
+			expr:   "a + b",
+			result: "a + b",
+		},
+		{
+			expr: "(a*b+c)/(a*b+c-t1) + (a*b)*(a-b)",
+			temps: []Temp{
+				{"t2", "a * b"},
+				{"t3", "t2 + c"},
+			},
+			result: "t3 / (t3 - t1) + t2 * (a - b)",
+		},
+		{
+			expr: "matrix(e*i/(a*(e*i)-b), -c/(a*(e*i)-b), 1, 2)",
+			temps: []Temp{
+				{"t1", "e * i"},
+				{"t2", "a * t1 - b"},
+			},
+			result: "matrix(t1 / t2, -c / t2, 1, 2)",
+		},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			temps, result, err := CSE(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(temps) != fmt.Sprint(tc.temps) {
+				t.Errorf("Temporary variables are not same \nActual : %v\nExpect : %v", temps, tc.temps)
+			}
+			if result != tc.result {
+				t.Errorf("Is not same \nActual : '%s'\nExpect : '%s'", result, tc.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package sm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/Konstantin8105/errors"
+)
+
+// Error is error of symbolic math with details about expression,
+// declarations and amount of iterations. Err is reason of error, for
+// example *MatrixShapeError, *ArityError, *DivisionByZeroError or
+// *UnsupportedError.
+type Error struct {
+	Err  error
+	tree errors.Tree
+}
+
+func (e *Error) Error() string {
+	return e.tree.Error()
+}
+
+// Unwrap return reason of error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Location of sub-expression in simplified expression and in input
+// expression.
+type Location struct {
+	// Expr is sub-expression
+	Expr string
+
+	// Pos is byte offset of sub-expression in simplified expression.
+	// If position is unknown, then value is -1.
+	Pos int
+
+	// Segment is index of segment of input expression separated by `;`,
+	// for example segment 1 in expression `a*x; constant(a)` is
+	// `constant(a)`. Column is byte column of sub-expression in that
+	// segment, first column is 1. If position is unknown, then
+	// values are -1.
+	Segment int
+	Column  int
+}
+
+func (l *Location) location() *Location {
+	return l
+}
+
+// locate sub-expression in simplified expression and in input expression
+func (l *Location) locate(s sm) {
+	l.Pos = position(s.base, l.Expr)
+	if 0 < l.Column {
+		// position is already known
+		return
+	}
+	l.Segment, l.Column = -1, -1
+	if s.segment < 0 || len(s.segments) <= s.segment {
+		return
+	}
+	if offset := match(s.segments[s.segment], l.Expr); 0 <= offset {
+		l.Segment, l.Column = s.segment, offset+1
+	}
+}
+
+// position return byte offset of `sub` in `expr` without taking into
+// account whitespaces. If `sub` is not found, then return -1.
+func position(expr, sub string) int {
+	var (
+		clean   []rune
+		offsets []int
+	)
+	for pos, r := range expr {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		clean = append(clean, r)
+		offsets = append(offsets, pos)
+	}
+	sub = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, sub)
+	if sub == "" {
+		return -1
+	}
+	index := strings.Index(string(clean), sub)
+	if index < 0 {
+		return -1
+	}
+	// index of rune
+	index = len([]rune(string(clean)[:index]))
+	return offsets[index]
+}
+
+type lexeme struct {
+	offset int
+	value  string
+}
+
+// lexemes of expression without parens. Numbers are in same format,
+// for example `2` and `2.000`.
+func lexemes(expr string) (ls []lexeme) {
+	items, _ := tokenize(expr)
+	for _, it := range items {
+		switch it.kind {
+		case itemLeft, itemRight:
+			continue
+		case itemNumber:
+			if r, ok := new(big.Rat).SetString(it.value); ok {
+				it.value = r.RatString()
+			}
+		}
+		ls = append(ls, lexeme{offset: it.offset, value: it.value})
+	}
+	return
+}
+
+// match return byte offset of `sub` in `expr`. Parens and formatting
+// of numbers are ignored, because simplified expression is not same
+// as input expression. If `sub` is not found fully, then offset of
+// longest matched begin of `sub` is returned. If nothing is matched,
+// then return -1.
+func match(expr, sub string) (offset int) {
+	es, ss := lexemes(expr), lexemes(sub)
+	offset = -1
+	best := 0
+	for i := range es {
+		amount := 0
+		for amount < len(ss) && i+amount < len(es) &&
+			es[i+amount].value == ss[amount].value {
+			amount++
+		}
+		if best < amount {
+			best, offset = amount, es[i].offset
+		}
+		if amount == len(ss) {
+			break
+		}
+	}
+	return
+}
+
+// caret return line with caret under column of line
+func caret(line string, column int) string {
+	var buf strings.Builder
+	for i, r := range line {
+		if column-1 <= i {
+			break
+		}
+		if r == '\t' {
+			buf.WriteRune('\t')
+			continue
+		}
+		buf.WriteRune(' ')
+	}
+	buf.WriteRune('^')
+	return buf.String()
+}
+
+// SyntaxError is error of not valid input expression or not valid
+// declaration of constant, variable or function.
+type SyntaxError struct {
+	Location
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Msg, e.Expr)
+}
+
+// MatrixShapeError is error of matrix with not valid shape, for example
+// determinant of not square matrix.
+type MatrixShapeError struct {
+	Location
+	Msg string
+}
+
+func (e *MatrixShapeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Msg, e.Expr)
+}
+
+// ArityError is error of function with not valid amount of arguments.
+type ArityError struct {
+	Location
+	Name   string
+	Args   int
+	Expect int
+}
+
+func (e *ArityError) Error() string {
+	return fmt.Sprintf("function `%s` have %d arguments, but expect %d: %s",
+		e.Name, e.Args, e.Expect, e.Expr)
+}
+
+// DivisionByZeroError is error of division by zero.
+type DivisionByZeroError struct {
+	Location
+}
+
+func (e *DivisionByZeroError) Error() string {
+	return fmt.Sprintf("cannot divide by zero: %s", e.Expr)
+}
+
+// UnsupportedError is error of not supported expression, for example
+// not valid number or division by matrix.
+type UnsupportedError struct {
+	Location
+	Msg string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Msg, e.Expr)
+}
+
+// EquivalenceError is error of not equivalent expressions. Bindings are
+// values of names, Index is index of value of matrix, Expect is value
+// of input expression and Actual is value of simplified expression.
+type EquivalenceError struct {
+	Bindings map[string]float64
+	Index    int
+	Expect   float64
+	Actual   float64
+	Msg      string
+}
+
+func (e *EquivalenceError) Error() string {
+	return fmt.Sprintf("%s: %g != %g for %v", e.Msg, e.Expect, e.Actual, e.Bindings)
+}
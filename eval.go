@@ -0,0 +1,376 @@
+package sm
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+
+	goast "go/ast"
+)
+
+// Eval return numeric value of expression. Values of names are in
+// bindings, names with subscripts are in form `x_1`. Expression is
+// calculated numerically, only parts with functions `d`, `integral`,
+// `inject`, `subs` and matrix operations are simplified before
+// evaluation.
+// Example:
+//
+//	expr     : "d(a*pow(x,3),x); constant(a); variable(x)"
+//	bindings : map[string]float64{"a": 2, "x": 0.5}
+//	out      : 1.5
+func Eval(expr string, bindings map[string]float64) (out float64, err error) {
+	s, a, err := evalPrepare(expr, bindings)
+	if err != nil {
+		return
+	}
+	if _, ok, _ := isMatrix(a); ok {
+		return 0, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(a)},
+			Msg:      "result is matrix, use function EvalMatrix",
+		})
+	}
+	return s.evaluate(a, bindings)
+}
+
+// EvalMatrix return numeric value of expression with matrix result.
+// Values of names are in bindings. For more details see Eval.
+// Example:
+//
+//	expr     : "transpose(matrix(a,2,3,4,2,2))"
+//	bindings : map[string]float64{"a": 1}
+//	out      : [][]float64{{1, 3}, {2, 4}}
+func EvalMatrix(expr string, bindings map[string]float64) (out [][]float64, err error) {
+	s, a, err := evalPrepare(expr, bindings)
+	if err != nil {
+		return
+	}
+	mt, ok, err := isMatrix(a)
+	if err != nil {
+		return nil, s.errorGen(err)
+	}
+	if !ok {
+		return nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(a)},
+			Msg:      "result is not matrix, use function Eval",
+		})
+	}
+	out = make([][]float64, mt.Rows)
+	for r := 0; r < mt.Rows; r++ {
+		out[r] = make([]float64, mt.Cols)
+		for c := 0; c < mt.Cols; c++ {
+			out[r][c], err = s.evaluate(mt.Args[mt.position(r, c)], bindings)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+// evalPrepare return expression for evaluation. Parts of expression
+// without functions `d`, `integral`, `subs` and matrix operations are
+// calculated by bindings without simplification, other parts are
+// simplified.
+func evalPrepare(expr string, bindings map[string]float64) (s sm, a goast.Expr, err error) {
+	if err = s.parse(expr); err != nil {
+		return
+	}
+	s.evalOptions()
+	a, err = parser.ParseExpr(s.base)
+	if err != nil {
+		return s, nil, s.errorGen(err)
+	}
+	a, err = s.reduce(a, bindings)
+	return
+}
+
+// evalOptions set options of simplification for evaluation.
+// Numbers are exact for avoid rounding.
+func (s *sm) evalOptions() {
+	s.opts = Options{Exact: true}.defaults()
+	s.out = s.opts.Out
+	s.ctx = context.Background()
+}
+
+// reduce replace parts of expression by numbers, if values of all
+// names are in bindings. Parts with functions, which cannot be
+// calculated numerically, are simplified. Arguments of matrix
+// operations are reduced before simplification.
+//
+//	expr     : d(pow(x,3),x) + pow(a+b,8)
+//	bindings : a = 1, b = 1
+//	result   : 3*pow(x,2) + 256
+func (s *sm) reduce(e goast.Expr, bindings map[string]float64) (r goast.Expr, err error) {
+	if evaluable(e) {
+		v, err := s.evaluate(e, bindings)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			// error is found by evaluation of result
+			return e, nil
+		}
+		lit := &goast.BasicLit{
+			Kind:  token.FLOAT,
+			Value: strconv.FormatFloat(math.Abs(v), 'g', -1, 64),
+		}
+		if v < 0 {
+			return &goast.UnaryExpr{Op: token.SUB, X: lit}, nil
+		}
+		return lit, nil
+	}
+	matrixOf := func(e goast.Expr) (goast.Expr, error) {
+		if _, ok, _ := isMatrix(e); !ok {
+			return e, nil
+		}
+		return s.simplify(e)
+	}
+	switch v := e.(type) {
+	case *goast.ParenExpr:
+		x, err := s.reduce(v.X, bindings)
+		if err != nil {
+			return nil, err
+		}
+		return matrixOf(&goast.ParenExpr{X: x})
+
+	case *goast.UnaryExpr:
+		x, err := s.reduce(v.X, bindings)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok, _ := isMatrix(x); ok {
+			return s.simplify(&goast.UnaryExpr{Op: v.Op, X: x})
+		}
+		return &goast.UnaryExpr{Op: v.Op, X: x}, nil
+
+	case *goast.BinaryExpr:
+		x, err := s.reduce(v.X, bindings)
+		if err != nil {
+			return nil, err
+		}
+		y, err := s.reduce(v.Y, bindings)
+		if err != nil {
+			return nil, err
+		}
+		r := &goast.BinaryExpr{X: x, Op: v.Op, Y: y}
+		_, okX, _ := isMatrix(x)
+		_, okY, _ := isMatrix(y)
+		if okX || okY {
+			return s.simplify(r)
+		}
+		return r, nil
+
+	case *goast.CallExpr:
+		id, ok := v.Fun.(*goast.Ident)
+		if !ok {
+			break
+		}
+		if call, ok, err := s.formCall(v); err != nil {
+			return nil, err
+		} else if ok {
+			// value of form is value of argument
+			return s.reduce(call.Args[0], bindings)
+		}
+		_, isEval := evalFunctions[id.Name]
+		switch {
+		case isEval, id.Name == matrix, id.Name == transpose,
+			id.Name == det, id.Name == inverse:
+			call := &goast.CallExpr{Fun: v.Fun}
+			for _, arg := range v.Args {
+				a, err := s.reduce(arg, bindings)
+				if err != nil {
+					return nil, err
+				}
+				call.Args = append(call.Args, a)
+			}
+			if isEval || id.Name == matrix {
+				return call, nil
+			}
+			r, err := s.simplify(call)
+			if err != nil {
+				return nil, err
+			}
+			return s.reduce(r, bindings)
+		}
+		// names of arguments are bound, for example `d(f,x)` or
+		// `nintegral(f,x,a,b)`
+		r, err := s.simplify(v)
+		if err != nil {
+			return nil, err
+		}
+		if astToStr(r) == astToStr(v) {
+			return r, nil
+		}
+		return s.reduce(r, bindings)
+	}
+	return e, nil
+}
+
+// evaluable return true, if expression is calculated by function
+// evaluate without simplification
+func evaluable(e goast.Expr) bool {
+	ok := true
+	goast.Inspect(e, func(n goast.Node) bool {
+		if call, isCall := n.(*goast.CallExpr); isCall {
+			id, isId := call.Fun.(*goast.Ident)
+			if !isId {
+				ok = false
+				return false
+			}
+			if f, found := evalFunctions[id.Name]; !found || f.args != len(call.Args) {
+				ok = false
+				return false
+			}
+			for _, arg := range call.Args {
+				ok = ok && evaluable(arg)
+			}
+			return false
+		}
+		return ok
+	})
+	return ok
+}
+
+// evaluateAll return numeric values of simplified expression. Values of
+// matrix are row by row.
+func (s sm) evaluateAll(e goast.Expr, bindings map[string]float64) (values []float64, err error) {
+	mt, ok, err := isMatrix(e)
+	if err != nil {
+		return nil, s.errorGen(err)
+	}
+	if !ok {
+		v, err := s.evaluate(e, bindings)
+		return []float64{v}, err
+	}
+	for i := range mt.Args {
+		v, err := s.evaluate(mt.Args[i], bindings)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return
+}
+
+// evaluate return numeric value of simplified expression
+func (s sm) evaluate(e goast.Expr, bindings map[string]float64) (float64, error) {
+	switch v := e.(type) {
+	case *goast.ParenExpr:
+		return s.evaluate(v.X, bindings)
+
+	case *goast.BasicLit:
+		if v.Kind == token.INT || v.Kind == token.FLOAT {
+			val, err := strconv.ParseFloat(v.Value, 64)
+			if err != nil {
+				return 0, s.errorGen(&UnsupportedError{
+					Location: Location{Expr: v.Value},
+					Msg:      fmt.Sprintf("not valid number: %v", err),
+				})
+			}
+			return val, nil
+		}
+
+	case *goast.Ident:
+		val, ok := bindings[external(v.Name)]
+		if !ok {
+			return 0, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: v.Name},
+				Msg:      "value of name is not found",
+			})
+		}
+		return val, nil
+
+	case *goast.UnaryExpr:
+		x, err := s.evaluate(v.X, bindings)
+		if err != nil {
+			return 0, err
+		}
+		switch v.Op {
+		case token.ADD:
+			return x, nil
+		case token.SUB:
+			return -x, nil
+		}
+
+	case *goast.BinaryExpr:
+		x, err := s.evaluate(v.X, bindings)
+		if err != nil {
+			return 0, err
+		}
+		y, err := s.evaluate(v.Y, bindings)
+		if err != nil {
+			return 0, err
+		}
+		switch v.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y == 0 {
+				return 0, s.errorGen(&DivisionByZeroError{
+					Location: Location{Expr: astToStr(v)},
+				})
+			}
+			return x / y, nil
+		}
+
+	case *goast.CallExpr:
+		id, ok := v.Fun.(*goast.Ident)
+		if !ok {
+			break
+		}
+		if id.Name == nintegralName && (len(v.Args) == 4 || len(v.Args) == 5) {
+			// numeric integral is not calculated in Exact mode
+			return s.nintegralValue(v)
+		}
+		f, ok := evalFunctions[id.Name]
+		if !ok {
+			break
+		}
+		if len(v.Args) != f.args {
+			return 0, s.errorGen(&ArityError{
+				Location: Location{Expr: astToStr(v)},
+				Name:     id.Name,
+				Args:     len(v.Args),
+				Expect:   f.args,
+			})
+		}
+		var args []float64
+		for i := range v.Args {
+			arg, err := s.evaluate(v.Args[i], bindings)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, arg)
+		}
+		return f.f(args...), nil
+	}
+	return 0, s.errorGen(&UnsupportedError{
+		Location: Location{Expr: astToStr(e)},
+		Msg:      "cannot evaluate",
+	})
+}
+
+// evalFunctions is numeric functions with amount of arguments
+var evalFunctions = map[string]struct {
+	args int
+	f    func(args ...float64) float64
+}{
+	pow:      {2, func(args ...float64) float64 { return math.Pow(args[0], args[1]) }},
+	sinName:  {1, func(args ...float64) float64 { return math.Sin(args[0]) }},
+	cosName:  {1, func(args ...float64) float64 { return math.Cos(args[0]) }},
+	tanName:  {1, func(args ...float64) float64 { return math.Tan(args[0]) }},
+	expName:  {1, func(args ...float64) float64 { return math.Exp(args[0]) }},
+	logName:  {1, func(args ...float64) float64 { return math.Log(args[0]) }},
+	sqrtName: {1, func(args ...float64) float64 { return math.Sqrt(args[0]) }},
+	asinName: {1, func(args ...float64) float64 { return math.Asin(args[0]) }},
+	acosName: {1, func(args ...float64) float64 { return math.Acos(args[0]) }},
+	atanName: {1, func(args ...float64) float64 { return math.Atan(args[0]) }},
+	sinhName: {1, func(args ...float64) float64 { return math.Sinh(args[0]) }},
+	coshName: {1, func(args ...float64) float64 { return math.Cosh(args[0]) }},
+	tanhName: {1, func(args ...float64) float64 { return math.Tanh(args[0]) }},
+	absName:  {1, func(args ...float64) float64 { return math.Abs(args[0]) }},
+}
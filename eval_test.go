@@ -0,0 +1,77 @@
+package sm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	for i, tc := range []struct {
+		expr     string
+		bindings map[string]float64
+		out      float64
+	}{
+		{"1/3+1/6", nil, 0.5},
+		{"a*x^2+b", map[string]float64{"a": 2, "x": 3, "b": -1}, 17},
+		{"d(a*pow(x,3),x); constant(a); variable(x)", map[string]float64{"a": 2, "x": 0.5}, 1.5},
+		{"integral(x*x,x,0,L); variable(x)", map[string]float64{"L": 3}, 9},
+		{"sin(x)^2+cos(x)^2", map[string]float64{"x": 0.7}, 1},
+		{"tan(x)", map[string]float64{"x": 0.3}, math.Tan(0.3)},
+		{"pow(a,0.5)", map[string]float64{"a": 2}, math.Sqrt2},
+		{"subs(u'*x, x, 2)", map[string]float64{"u'": 4}, 8},
+		{"det(matrix(a,1,2,b,2,2))", map[string]float64{"a": 3, "b": 4}, 10},
+		{"pow(a+b+c+d,8)", map[string]float64{"a": 1, "b": 2, "c": 3, "d": 4}, 1e8},
+		{"pow(a+b+c+d+e+f,6)/pow(a+b,3)", map[string]float64{"a": 1, "b": 1, "c": 1, "d": 1, "e": 1, "f": 1}, 5832},
+		{"d(pow(x,3),x)+pow(a+b,8); variable(x)", map[string]float64{"a": 1, "b": 1, "x": 2}, 268},
+		{"det(matrix(pow(a+b,8),1,2,b,2,2))", map[string]float64{"a": 1, "b": 1}, 254},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			out, err := Eval(tc.expr, tc.bindings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(out-tc.out) > 1e-12 {
+				t.Fatalf("not same: %v != %v", out, tc.out)
+			}
+		})
+	}
+}
+
+func TestEvalMatrix(t *testing.T) {
+	out, err := EvalMatrix("transpose(matrix(a,2,3,4,2,2))*b", map[string]float64{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out) != "[[2 6] [4 8]]" {
+		t.Fatalf("not same: %v", out)
+	}
+	if _, err := EvalMatrix("a", map[string]float64{"a": 1}); err == nil {
+		t.Fatalf("error is not found for not matrix")
+	}
+	if _, err := Eval("matrix(1,2,1,2)", nil); err == nil {
+		t.Fatalf("error is not found for matrix")
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	for i, tc := range []struct {
+		expr   string
+		target interface{}
+	}{
+		{"a+b", new(*UnsupportedError)},
+		{"1/(a-1)", new(*DivisionByZeroError)},
+		{"f(a)", new(*UnsupportedError)},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			_, err := Eval(tc.expr, map[string]float64{"a": 1})
+			if err == nil {
+				t.Fatalf("error is not found")
+			}
+			if !errors.As(err, tc.target) {
+				t.Fatalf("not valid type of error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package sm
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+
+	goast "go/ast"
+)
+
+// Expr is expression tree of symbolic math with declarations of
+// constants, variables and functions used in that expression.
+type Expr struct {
+	ast  goast.Expr
+	cons []string
+	vars []string
+	funs []function
+}
+
+// String return expression in Sexpr syntax without declarations.
+func (e Expr) String() string {
+	if e.ast == nil {
+		return ""
+	}
+	return external(astToStr(e.ast))
+}
+
+// Parse expression in Sexpr syntax. Keywords `constant`, `variable`,
+// `function` are allowed.
+func Parse(expr string) (e Expr, err error) {
+	var s sm
+	if err = s.parse(expr); err != nil {
+		return
+	}
+	e.ast, err = parser.ParseExpr(s.base)
+	if err != nil {
+		return e, s.errorGen(err)
+	}
+	e.cons = s.cons
+	e.vars = s.vars
+	e.funs = s.funs
+	return
+}
+
+// Num is numeric value.
+func Num(v float64) Expr {
+	lit := &goast.BasicLit{Kind: token.FLOAT}
+	if v == float64(int64(v)) {
+		lit.Kind = token.INT
+	}
+	if v < 0 {
+		lit.Value = strconv.FormatFloat(-v, 'g', -1, 64)
+		return Expr{ast: &goast.UnaryExpr{Op: token.SUB, X: lit}}
+	}
+	lit.Value = strconv.FormatFloat(v, 'g', -1, 64)
+	return Expr{ast: lit}
+}
+
+// Const is constant with name, same as keyword `constant(name)`.
+// Names are in Sexpr syntax, for example `u'` or `x_1`.
+func Const(name string) Expr {
+	name = internal(name)
+	return Expr{ast: goast.NewIdent(name), cons: []string{name}}
+}
+
+// Var is variable with name, same as keyword `variable(name)`.
+func Var(name string) Expr {
+	name = internal(name)
+	return Expr{ast: goast.NewIdent(name), vars: []string{name}}
+}
+
+// Func is function with name depend on variables, same as keyword
+// `function(name, variables...)`.
+func Func(name string, variables ...string) Expr {
+	name = internal(name)
+	var vars []string
+	for _, v := range variables {
+		vars = append(vars, internal(v))
+	}
+	f := function{name: name, variables: vars}
+	return Expr{
+		ast:  goast.NewIdent(name),
+		vars: append([]string{}, vars...),
+		funs: []function{f},
+	}
+}
+
+// Add is summation of expressions: es[0] + es[1] + ...
+func Add(es ...Expr) Expr {
+	return binary(token.ADD, es...)
+}
+
+// Sub is subtraction of expressions: a - b
+func Sub(a, b Expr) Expr {
+	return binary(token.SUB, a, b)
+}
+
+// Mul is multiplication of expressions: es[0] * es[1] * ...
+func Mul(es ...Expr) Expr {
+	return binary(token.MUL, es...)
+}
+
+// Quo is division of expressions: a / b
+func Quo(a, b Expr) Expr {
+	return binary(token.QUO, a, b)
+}
+
+// Neg is negative expression: -a
+func Neg(a Expr) Expr {
+	r := merge(a)
+	r.ast = &goast.UnaryExpr{Op: token.SUB, X: paren(a.ast)}
+	return r
+}
+
+// Pow is power of expression: pow(base, exp)
+func Pow(base, exp Expr) Expr {
+	return Call(pow, base, exp)
+}
+
+// Matrix is matrix with amount of rows and columns. Arguments are
+// values of matrix row by row. If amount of arguments is not
+// rows*cols, then simplification return error *MatrixShapeError.
+func Matrix(rows, cols int, args ...Expr) Expr {
+	all := append([]Expr{}, args...)
+	all = append(all, Num(float64(rows)), Num(float64(cols)))
+	return Call(matrix, all...)
+}
+
+// Call is call of function by name, for example:
+//
+//	Call("d", f, Var("x"))                 is d(f, x)
+//	Call("integral", f, Var("x"), a, b)    is integral(f, x, a, b)
+//	Call("transpose", m)                   is transpose(m)
+func Call(name string, args ...Expr) Expr {
+	r := merge(args...)
+	call := &goast.CallExpr{Fun: goast.NewIdent(internal(name))}
+	for i := range args {
+		call.Args = append(call.Args, args[i].ast)
+	}
+	r.ast = call
+	return r
+}
+
+func binary(op token.Token, es ...Expr) Expr {
+	if len(es) == 0 {
+		return Num(0)
+	}
+	r := merge(es...)
+	r.ast = paren(es[0].ast)
+	for i := 1; i < len(es); i++ {
+		r.ast = &goast.BinaryExpr{X: r.ast, Op: op, Y: paren(es[i].ast)}
+	}
+	return r
+}
+
+// paren is protection of expression precedence
+func paren(e goast.Expr) goast.Expr {
+	switch e.(type) {
+	case *goast.BasicLit, *goast.Ident, *goast.CallExpr, *goast.ParenExpr:
+		return e
+	}
+	return &goast.ParenExpr{X: e}
+}
+
+// merge declarations of expressions
+func merge(es ...Expr) (r Expr) {
+	has := func(list []string, name string) bool {
+		for i := range list {
+			if list[i] == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, e := range es {
+		for _, c := range e.cons {
+			if !has(r.cons, c) {
+				r.cons = append(r.cons, c)
+			}
+		}
+		for _, v := range e.vars {
+			if !has(r.vars, v) {
+				r.vars = append(r.vars, v)
+			}
+		}
+	funs:
+		for _, f := range e.funs {
+			for i := range r.funs {
+				if r.funs[i].name == f.name &&
+					fmt.Sprint(r.funs[i].variables) == fmt.Sprint(f.variables) {
+					continue funs
+				}
+			}
+			r.funs = append(r.funs, f)
+		}
+	}
+	return
+}
+
+// Substitute return expression with names replaced by values. All
+// names are replaced simultaneously, for example substitution of `x`
+// by `y` and `y` by `x` in expression `x - y` is `y - x`.
+func Substitute(e Expr, values map[string]Expr) Expr {
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	es := []Expr{e}
+	vs := map[string]goast.Expr{}
+	for _, name := range names {
+		es = append(es, values[name])
+		vs[internal(name)] = values[name].ast
+	}
+	r := merge(es...)
+	r.ast = substitute(e.ast, vs)
+	return r
+}
+
+// Simplify return simplified expression.
+func Simplify(e Expr, opts Options) (r Expr, err error) {
+	return SimplifyContext(context.Background(), e, opts)
+}
+
+// SimplifyContext return simplified expression. If context is canceled
+// or deadline is exceeded, then return error *CancelError.
+func SimplifyContext(ctx context.Context, e Expr, opts Options) (r Expr, err error) {
+	var s sm
+	s.opts = opts.defaults()
+	s.out = s.opts.Out
+	s.ctx = ctx
+	if e.ast == nil {
+		return Expr{}, s.errorGen(&UnsupportedError{Msg: "expression is empty"})
+	}
+	s.base = astToStr(e.ast)
+	s.segments = []string{s.base}
+	s.cons = append([]string{}, e.cons...)
+	s.vars = append([]string{}, e.vars...)
+	s.funs = append([]function{}, e.funs...)
+
+	out, err := s.run()
+	if err != nil {
+		return Expr{}, s.cancelError(err)
+	}
+	out, err = s.outputForms(out)
+	if err != nil {
+		return Expr{}, err
+	}
+	out, err = s.approximate(out)
+	if err != nil {
+		return Expr{}, err
+	}
+	r = merge(e)
+	r.ast, err = parser.ParseExpr(out)
+	if err != nil {
+		return Expr{}, s.errorGen(err)
+	}
+	return
+}
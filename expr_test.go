@@ -0,0 +1,195 @@
+package sm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpr(t *testing.T) {
+	x := Var("x")
+	a := Const("a")
+	u := Func("u", "x")
+	for i, tc := range []struct {
+		e   Expr
+		str string
+		out string
+	}{
+		{
+			e:   Add(Num(1), Num(2)),
+			str: "1 + 2",
+			out: "3.000",
+		},
+		{
+			e:   Mul(a, Add(Num(2), Num(-8))),
+			str: "a * (2 + (-8))",
+			out: "-6.000*a",
+		},
+		{
+			e:   Pow(Add(a, Num(1)), Num(2)),
+			str: "pow(a+1, 2)",
+			out: "1.000+2.000*a+pow(a,2.000)",
+		},
+		{
+			e:   Call("d", Mul(Num(2), Pow(x, a)), x),
+			str: "d(2*pow(x, a), x)",
+			out: "2.000*(a*pow(x,-1.000+a))",
+		},
+		{
+			e:   Call("d", Mul(u, x), x),
+			str: "d(u*x, x)",
+			out: "d(u,x)*x+u",
+		},
+		{
+			e:   Quo(Neg(a), Sub(x, a)),
+			str: "(-a) / (x - a)",
+			out: "-(a/(x-a))",
+		},
+		{
+			e:   Substitute(Mul(Const("u'"), Var("x₁"), Num(2)), map[string]Expr{"x_1": Num(3)}),
+			str: "u' * 3 * 2",
+			out: "6.000*u'",
+		},
+		{
+			e:   Mul(Num(2), Matrix(1, 1, Add(Num(2), Num(5)))),
+			str: "2 * matrix(2+5, 1, 1)",
+			out: "matrix(14.000,1.000,1.000)",
+		},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.str), func(t *testing.T) {
+			if act, exp := strings.Replace(tc.e.String(), " ", "", -1),
+				strings.Replace(tc.str, " ", "", -1); act != exp {
+				t.Fatalf("String is not same \nActual : '%s'\nExpect : '%s'", act, exp)
+			}
+			r, err := Simplify(tc.e, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			act := strings.Replace(r.String(), " ", "", -1)
+			if act != tc.out {
+				t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, tc.out)
+			}
+		})
+	}
+}
+
+func TestExprErrors(t *testing.T) {
+	for i, tc := range []struct {
+		e      Expr
+		target interface{}
+	}{
+		{Matrix(2, 2, Num(1), Num(2), Num(3)), new(*MatrixShapeError)},
+		{Mul(Var("x"), Matrix(1, 2, Num(1))), new(*MatrixShapeError)},
+		{Expr{}, new(*UnsupportedError)},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.e), func(t *testing.T) {
+			_, err := Simplify(tc.e, Options{})
+			if !errors.As(err, tc.target) {
+				t.Fatalf("not valid error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	e, err := Parse("d(pow(x,3),x);variable(x);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := Simplify(Mul(Const("a"), e), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := strings.Replace(r.String(), " ", "", -1); act != "3.000*a*pow(x,2.000)" {
+		t.Fatalf("not same: %s", act)
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		out  string
+	}{
+		{"u'*x + u'*x; constant(u'); variable(x)", "2.000*u'*x"},
+		{"λ*x₁ + λ*x_1; constant(λ); variable(x_1)", "2.000*λ*x_1"},
+	} {
+		e, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := Simplify(e, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := strings.Replace(r.String(), " ", "", -1); act != tc.out {
+			t.Fatalf("not same: %s", act)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	x, y := Var("x"), Var("y")
+	ax := Const("ax")
+	e := Substitute(Sub(Mul(ax, x), y), map[string]Expr{
+		"x": y,
+		"y": Add(x, Num(1)),
+	})
+	if act := strings.Replace(e.String(), " ", "", -1); act != "(ax*y)-(x+1)" {
+		t.Fatalf("not same: %s", act)
+	}
+	r, err := Simplify(e, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act := strings.Replace(r.String(), " ", "", -1); act != "-1.000+ax*y-x" {
+		t.Fatalf("not same: %s", act)
+	}
+}
+
+func TestSubstituteBound(t *testing.T) {
+	x, y, z, a := Var("x"), Var("y"), Var("z"), Const("a")
+	values := map[string]Expr{"x": Num(5), "y": Num(7), "z": Num(9), "a": Num(2)}
+	tcs := []struct {
+		e   Expr
+		out string
+	}{
+		{
+			e:   Call("integral", Mul(a, x), x),
+			out: "integral(2*x,x)",
+		},
+		{
+			e:   Call("integral", Mul(a, x), x, Num(0), Add(a, y)),
+			out: "integral(2*x,x,0,2+7)",
+		},
+		{
+			e:   Call("integral2", Mul(x, y), x, Num(0), a, y, Num(0), Add(x, z)),
+			out: "integral2(x*y,x,0,2,y,0,x+9)",
+		},
+		{
+			e:   Call("integral3", Mul(Mul(x, y), z), x, Num(0), a, y, x, Num(1), z, y, Add(x, a)),
+			out: "integral3((x*y)*z,x,0,2,y,x,1,z,y,x+2)",
+		},
+	}
+	for _, tc := range tcs {
+		e := Substitute(tc.e, values)
+		if act := strings.Replace(e.String(), " ", "", -1); act != tc.out {
+			t.Errorf("%s: not same: %s", tc.e, act)
+		}
+	}
+}
+
+func ExampleSimplify() {
+	x := Var("x")
+	e := Call("d", Pow(x, Num(3)), x)
+	r, err := Simplify(e, Options{})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stdout, "%s\n", e)
+	fmt.Fprintf(os.Stdout, "%s\n", r)
+	// Output:
+	// d(pow(x, 3), x)
+	// 3.000 * pow(x, 2.000)
+}
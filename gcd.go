@@ -0,0 +1,517 @@
+package sm
+
+import (
+	"fmt"
+	"go/token"
+	"math/big"
+	"sort"
+
+	goast "go/ast"
+)
+
+// one return polynomial with value 1
+func (p polynomial) one() polynomial {
+	return polynomial{
+		terms: []monomial{{coeff: big.NewRat(1, 1), powers: map[string]int{}}},
+		atoms: p.atoms,
+	}
+}
+
+// merge return atoms of both polynomials
+func (p polynomial) merge(q polynomial) map[string]goast.Expr {
+	atoms := map[string]goast.Expr{}
+	for name, e := range p.atoms {
+		atoms[name] = e
+	}
+	for name, e := range q.atoms {
+		atoms[name] = e
+	}
+	return atoms
+}
+
+// times return product of polynomial and monomial
+func (p polynomial) times(by monomial) (r polynomial) {
+	r.atoms = p.atoms
+	for _, m := range p.terms {
+		d := monomial{coeff: new(big.Rat).Mul(m.coeff, by.coeff), powers: map[string]int{}}
+		for name, n := range m.powers {
+			d.powers[name] = n
+		}
+		for name, n := range by.powers {
+			d.powers[name] += n
+		}
+		r.add(d)
+	}
+	return
+}
+
+// at return polynomial with atom replaced by polynomial
+func (p polynomial) at(name string, value polynomial) (r polynomial) {
+	r.atoms = p.merge(value)
+	for _, m := range p.terms {
+		t := monomial{coeff: m.coeff, powers: map[string]int{}}
+		for atom, n := range m.powers {
+			if atom != name {
+				t.powers[atom] = n
+			}
+		}
+		f := p.one().times(t)
+		for k := 0; k < m.powers[name]; k++ {
+			f = f.mul(value)
+		}
+		r = r.plus(f)
+	}
+	return
+}
+
+// mul return product of polynomials
+func (p polynomial) mul(q polynomial) (r polynomial) {
+	r.atoms = p.merge(q)
+	for _, m := range q.terms {
+		for _, t := range p.times(m).terms {
+			r.add(t)
+		}
+	}
+	return
+}
+
+// plus return sum of polynomials
+func (p polynomial) plus(q polynomial) (r polynomial) {
+	r.atoms = p.merge(q)
+	for _, ts := range [][]monomial{p.terms, q.terms} {
+		for _, m := range ts {
+			r.add(monomial{coeff: new(big.Rat).Set(m.coeff), powers: m.powers})
+		}
+	}
+	return
+}
+
+// sub return difference of polynomials
+func (p polynomial) sub(q polynomial) polynomial {
+	return p.plus(q.times(monomial{coeff: big.NewRat(-1, 1), powers: map[string]int{}}))
+}
+
+// vars return sorted names of atoms with not zero powers of both
+// polynomials
+func (p polynomial) vars(qs ...polynomial) (names []string) {
+	found := map[string]bool{}
+	for _, p := range append([]polynomial{p}, qs...) {
+		for _, m := range p.terms {
+			for name := range m.powers {
+				if !found[name] {
+					found[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// integral return true for polynomial without negative powers
+func (p polynomial) integral() bool {
+	for _, m := range p.terms {
+		for _, n := range m.powers {
+			if n < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// constant return true for polynomial without atoms
+func (p polynomial) constant() bool {
+	return len(p.vars()) == 0
+}
+
+// degreeOf return maximal power of atom
+func (p polynomial) degreeOf(name string) (d int) {
+	for _, m := range p.terms {
+		if d < m.powers[name] {
+			d = m.powers[name]
+		}
+	}
+	return
+}
+
+// coefficientOf return coefficient of polynomial by power of atom
+func (p polynomial) coefficientOf(name string, k int) (r polynomial) {
+	r.atoms = p.atoms
+	for _, m := range p.terms {
+		if m.powers[name] != k {
+			continue
+		}
+		d := monomial{coeff: new(big.Rat).Set(m.coeff), powers: map[string]int{}}
+		for atom, n := range m.powers {
+			if atom != name {
+				d.powers[atom] = n
+			}
+		}
+		r.add(d)
+	}
+	return
+}
+
+// leading return leading monomial in lexicographic order of atoms
+func (p polynomial) leading(names []string) monomial {
+	lead := p.terms[0]
+	for _, m := range p.terms[1:] {
+		for _, name := range names {
+			if m.powers[name] != lead.powers[name] {
+				if lead.powers[name] < m.powers[name] {
+					lead = m
+				}
+				break
+			}
+		}
+	}
+	return lead
+}
+
+// quo return quotient of polynomials. Result is not ok, if remainder
+// is not zero.
+func (p polynomial) quo(by polynomial) (q polynomial, ok bool) {
+	q.atoms = p.merge(by)
+	if len(by.terms) == 0 {
+		return q, false
+	}
+	names := p.vars(by)
+	lb := by.leading(names)
+	r := p
+	for 0 < len(r.terms) {
+		lr := r.leading(names)
+		t := monomial{coeff: new(big.Rat).Quo(lr.coeff, lb.coeff), powers: map[string]int{}}
+		for name, n := range lr.powers {
+			t.powers[name] = n
+		}
+		for name, n := range lb.powers {
+			t.powers[name] -= n
+			if t.powers[name] < 0 {
+				return q, false
+			}
+		}
+		q.add(monomial{coeff: new(big.Rat).Set(t.coeff), powers: t.powers})
+		r = r.sub(by.times(t))
+	}
+	return q, true
+}
+
+// quoRem return quotient and remainder of polynomials by atom. Leading
+// coefficient of divisor by atom is monomial.
+//
+//	x*x + 1 = (x - 1)*(x + 1) + 2
+func (p polynomial) quoRem(by polynomial, name string) (q, r polynomial) {
+	d := by.degreeOf(name)
+	lead := by.coefficientOf(name, d).terms[0].inverse()
+	q.atoms = p.merge(by)
+	r = p
+	for 0 < len(r.terms) && d <= r.degreeOf(name) {
+		k := r.degreeOf(name)
+		t := r.coefficientOf(name, k).times(lead).times(monomial{
+			coeff:  big.NewRat(1, 1),
+			powers: map[string]int{name: k - d},
+		})
+		q = q.plus(t)
+		r = r.sub(t.mul(by))
+	}
+	return
+}
+
+// prem return pseudo-remainder of polynomials by atom
+func (p polynomial) prem(by polynomial, name string) polynomial {
+	d := by.degreeOf(name)
+	lb := by.coefficientOf(name, d)
+	r := p
+	for 0 < len(r.terms) && d <= r.degreeOf(name) {
+		k := r.degreeOf(name)
+		t := r.coefficientOf(name, k).times(monomial{
+			coeff:  big.NewRat(1, 1),
+			powers: map[string]int{name: k - d},
+		})
+		r = r.mul(lb).sub(t.mul(by))
+	}
+	return r
+}
+
+// contentOf return greatest common divisor of coefficients by atom
+func (p polynomial) contentOf(name string) (g polynomial) {
+	g.atoms = p.atoms
+	for k := 0; k <= p.degreeOf(name); k++ {
+		if c := p.coefficientOf(name, k); 0 < len(c.terms) {
+			g = g.gcd(c)
+		}
+		if 0 < len(g.terms) && g.constant() {
+			break
+		}
+	}
+	return
+}
+
+// primitive return polynomial divided by content by atom
+func (p polynomial) primitive(name string) polynomial {
+	q, _ := p.quo(p.contentOf(name))
+	return q
+}
+
+// normalize return polynomial with integer coefficients without common
+// divisor and positive leading monomial
+func (p polynomial) normalize() polynomial {
+	if len(p.terms) == 0 {
+		return p
+	}
+	lcm := big.NewInt(1)
+	for _, m := range p.terms {
+		g := new(big.Int).GCD(nil, nil, lcm, m.coeff.Denom())
+		lcm.Mul(lcm, new(big.Int).Quo(m.coeff.Denom(), g))
+	}
+	r := p.times(monomial{coeff: new(big.Rat).SetInt(lcm), powers: map[string]int{}})
+	c := r.content()
+	if r.leading(r.vars()).coeff.Sign() < 0 {
+		c.Neg(c)
+	}
+	return r.times(monomial{coeff: c.Inv(c), powers: map[string]int{}})
+}
+
+// gcd return greatest common divisor of polynomials without negative
+// powers. Coefficients are rational, so that result is normalized.
+//
+//	gcd(x*x-1, x*x+2*x+1) = x+1
+func (p polynomial) gcd(q polynomial) polynomial {
+	switch {
+	case len(p.terms) == 0:
+		return q.normalize()
+	case len(q.terms) == 0:
+		return p.normalize()
+	}
+	names := p.vars(q)
+	if len(names) == 0 {
+		return p.one()
+	}
+	name := names[0]
+	switch {
+	case p.degreeOf(name) == 0:
+		return p.gcd(q.contentOf(name))
+	case q.degreeOf(name) == 0:
+		return q.gcd(p.contentOf(name))
+	}
+
+	// primitive polynomial remainder sequence
+	c := p.contentOf(name).gcd(q.contentOf(name))
+	a, b := p.primitive(name), q.primitive(name)
+	if a.degreeOf(name) < b.degreeOf(name) {
+		a, b = b, a
+	}
+	for 0 < len(b.terms) {
+		r := a.prem(b, name)
+		a = b
+		if 0 < len(r.terms) && r.degreeOf(name) == 0 {
+			a = a.one()
+			break
+		}
+		if 0 < len(r.terms) {
+			r = r.primitive(name)
+		}
+		b = r
+	}
+	if 0 < a.degreeOf(name) {
+		a = a.primitive(name)
+	}
+	r := c.mul(a).normalize()
+	r.atoms = p.merge(q)
+	return r
+}
+
+// cancelQuo return fraction of polynomials without common divisor.
+// Fraction with monomial denominator is not changed, because same
+// factors of numerator and denominator are cancelled by rule
+// `binaryNumber`.
+//
+//	(x*x-1)/(x-1) = x+1
+func (s *sm) cancelQuo(num, den goast.Expr) (r goast.Expr, ok bool) {
+	if hasCall(num, matrix) || hasCall(den, matrix) {
+		return nil, false
+	}
+	n, d := s.poly(num), s.poly(den)
+	if !n.integral() || !d.integral() || len(d.terms) < 2 || len(n.terms) == 0 {
+		return nil, false
+	}
+	g := n.gcd(d)
+	if g.constant() {
+		return nil, false
+	}
+	n, _ = n.quo(g)
+	d, _ = d.quo(g)
+	return s.fraction(n, d), true
+}
+
+// fraction return quotient of polynomials
+func (s *sm) fraction(n, d polynomial) goast.Expr {
+	if d.constant() {
+		return s.polyToAst(n.times(monomial{
+			coeff:  new(big.Rat).Inv(d.terms[0].coeff),
+			powers: map[string]int{},
+		}))
+	}
+	s.sortTerms(n)
+	s.sortTerms(d)
+	negative := monomial{coeff: big.NewRat(-1, 1), powers: map[string]int{}}
+	if d.terms[0].coeff.Sign() < 0 {
+		n, d = n.times(negative), d.times(negative)
+	}
+	// from : -a/(b+c)
+	// to   : -(a/(b+c))
+	if len(n.terms) == 1 && n.terms[0].coeff.Sign() < 0 {
+		return &goast.UnaryExpr{Op: token.SUB, X: &goast.ParenExpr{X: s.fraction(n.times(negative), d)}}
+	}
+	return &goast.BinaryExpr{X: s.polyToAst(n), Op: token.QUO, Y: s.polyToAst(d)}
+}
+
+// together return numerator and denominator of sum of fractions.
+// Result is not ok for not polynomial numerator or denominator.
+//
+//	1/x + 1/y = (y + x)/(x*y)
+func (s *sm) together(e goast.Expr) (n, d polynomial, ok bool) {
+	p := s.poly(e)
+	n.atoms, d.atoms = p.atoms, p.atoms
+	d = d.one()
+
+	// polynomials of atoms
+	polys := map[string]polynomial{}
+	for name, atom := range p.atoms {
+		a := s.poly(atom)
+		if !a.integral() {
+			return n, d, false
+		}
+		polys[name] = a
+	}
+	power := func(name string, k int) polynomial {
+		r := d.one()
+		for ; 0 < k; k-- {
+			r = r.mul(polys[name])
+		}
+		return r
+	}
+
+	// common denominator
+	common := map[string]int{}
+	for _, m := range p.terms {
+		for name, k := range m.powers {
+			if k < 0 && common[name] < -k {
+				common[name] = -k
+			}
+		}
+	}
+	for name, k := range common {
+		d = d.mul(power(name, k))
+	}
+	for _, m := range p.terms {
+		t := d.one().times(monomial{coeff: m.coeff, powers: map[string]int{}})
+		for name, k := range m.powers {
+			if 0 < k {
+				t = t.mul(power(name, k))
+			}
+		}
+		for name, k := range common {
+			t = t.mul(power(name, k+m.powers[name]))
+		}
+		n = n.plus(t)
+	}
+	return n, d, true
+}
+
+// cancel is fraction without common divisor of numerator and
+// denominator
+//
+//	cancel(x*x/(x-1) - 1/(x-1)) = x + 1
+func (s *sm) cancel(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != cancelName {
+		return false, nil, nil
+	}
+	if len(call.Args) != 1 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     cancelName,
+			Args:     len(call.Args),
+			Expect:   1,
+		})
+	}
+	cancel := func(e goast.Expr) goast.Expr {
+		if hasCall(e, matrix) {
+			return e
+		}
+		n, d, ok := s.together(e)
+		if !ok {
+			return e
+		}
+		if g := n.gcd(d); 0 < len(n.terms) {
+			n, _ = n.quo(g)
+			d, _ = d.quo(g)
+		}
+		return s.fraction(n, d)
+	}
+	mt, ok, err := isMatrix(call.Args[0])
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
+	if !ok {
+		return true, cancel(call.Args[0]), nil
+	}
+	for i := range mt.Args {
+		mt.Args[i] = cancel(mt.Args[i])
+	}
+	return true, s.matrixToAst(mt), nil
+}
+
+// gcd is greatest common divisor of polynomials
+//
+//	gcd(2*x*x-2, 4*x+4) = 2*(x+1)
+func (s *sm) gcd(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != gcdName {
+		return false, nil, nil
+	}
+	if len(call.Args) != 2 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     gcdName,
+			Args:     len(call.Args),
+			Expect:   2,
+		})
+	}
+	var ps [2]polynomial
+	for i := range ps {
+		ps[i] = s.poly(call.Args[i])
+		if !ps[i].integral() || hasCall(call.Args[i], matrix) {
+			return false, nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(call)},
+				Msg:      fmt.Sprintf("argument is not polynomial: %s", astToStr(call.Args[i])),
+			})
+		}
+	}
+	// common integer factor
+	c := new(big.Int).GCD(nil, nil, ps[0].content().Num(), ps[1].content().Num())
+	g := ps[0].gcd(ps[1])
+	if len(g.terms) == 0 {
+		return true, s.createFloat(0), nil
+	}
+	g = g.times(monomial{coeff: new(big.Rat).SetInt(c), powers: map[string]int{}})
+	s.sortTerms(g)
+	return true, s.polyToAst(g), nil
+}
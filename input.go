@@ -0,0 +1,489 @@
+package sm
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	goast "go/ast"
+)
+
+// prime is letter of prime `'` in names, because symbol `'` is not
+// allowed in Go names. Rune is U+02B9 MODIFIER LETTER PRIME.
+const prime = "ʹ"
+
+// external return expression with names of input syntax
+func external(expr string) string {
+	return strings.Replace(expr, prime, "'", -1)
+}
+
+// internal return name in form of tokenizer, for example names `u'`
+// and `x₁` are `uʹ` and `x_1`
+func internal(name string) string {
+	items, err := tokenize(name)
+	if err != nil || len(items) != 1 || items[0].kind != itemName {
+		return strings.Replace(name, "'", prime, -1)
+	}
+	return items[0].value
+}
+
+type itemKind int
+
+const (
+	itemNumber itemKind = iota
+	itemName
+	itemOperator // + - * / ^
+	itemLeft     // (
+	itemRight    // )
+	itemComma
+)
+
+// item is token of input expression
+type item struct {
+	kind   itemKind
+	value  string // normalized value
+	offset int    // byte offset in input expression
+	end    int    // byte offset after item
+}
+
+func (it item) String() string {
+	return "`" + it.value + "`"
+}
+
+// tokenize input expression. Numbers are in scientific notation, for
+// example `1.5e-3`. Names are unicode letters, digits and symbol `_`
+// with subscripts and primes:
+//
+//	`x_1`, `x₁`  : name `x_1`
+//	`x_{ij}`     : name `x_ij`
+//	`u'`, `u''`  : names `uʹ`, `uʹʹ`
+//
+// Operator `**` is same as `^`, operators `·`, `×`, `−`, `÷` are
+// `*`, `*`, `-`, `/`. If error is found, then items before error are
+// returned.
+func tokenize(expr string) (items []item, err *SyntaxError) {
+	syntax := func(offset int, msg string) *SyntaxError {
+		return &SyntaxError{
+			Location: Location{Expr: expr, Column: offset + 1},
+			Msg:      msg,
+		}
+	}
+	next := func(offset int) rune {
+		r, _ := utf8.DecodeRuneInString(expr[offset:])
+		return r
+	}
+	isDigit := func(r rune) bool { return '0' <= r && r <= '9' }
+	isSubscript := func(r rune) bool { return '₀' <= r && r <= '₉' }
+
+	for offset := 0; offset < len(expr); {
+		r, size := utf8.DecodeRuneInString(expr[offset:])
+		switch {
+		case unicode.IsSpace(r):
+			offset += size
+
+		case isDigit(r) || (r == '.' && isDigit(next(offset+1))):
+			begin := offset
+			for offset < len(expr) && isDigit(next(offset)) {
+				offset++
+			}
+			if next(offset) == '.' {
+				offset++
+				for offset < len(expr) && isDigit(next(offset)) {
+					offset++
+				}
+			}
+			// exponent is only before digits, because `2e` is `2*e`
+			if e := next(offset); e == 'e' || e == 'E' {
+				end := offset + 1
+				if sign := next(end); sign == '+' || sign == '-' {
+					end++
+				}
+				if isDigit(next(end)) {
+					offset = end
+					for offset < len(expr) && isDigit(next(offset)) {
+						offset++
+					}
+				}
+			}
+			if next(offset) == '.' {
+				return items, syntax(offset, "not valid number")
+			}
+			items = append(items, item{kind: itemNumber, value: expr[begin:offset], offset: begin, end: offset})
+
+		case unicode.IsLetter(r) || r == '_':
+			begin := offset
+			var name strings.Builder
+			subscript := false
+		name:
+			for offset < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[offset:])
+				switch {
+				case r == '_' && next(offset+1) == '{':
+					end := strings.IndexRune(expr[offset:], '}')
+					if end < 0 {
+						return items, syntax(offset, "subscript is not closed by `}`")
+					}
+					index := expr[offset+2 : offset+end]
+					if index == "" || 0 <= strings.IndexFunc(index, func(r rune) bool {
+						return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+					}) {
+						return items, syntax(offset, "not valid subscript")
+					}
+					name.WriteString("_" + index)
+					size = end + 1
+					subscript = false
+				case isSubscript(r):
+					if !subscript {
+						name.WriteRune('_')
+					}
+					name.WriteRune('0' + r - '₀')
+					subscript = true
+				case r == '\'':
+					name.WriteString(prime)
+					subscript = false
+				case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+					name.WriteRune(r)
+					subscript = false
+				default:
+					break name
+				}
+				offset += size
+			}
+			if token.Lookup(name.String()).IsKeyword() {
+				return items, syntax(begin, "reserved name")
+			}
+			items = append(items, item{kind: itemName, value: name.String(), offset: begin, end: offset})
+
+		default:
+			it := item{kind: itemOperator, offset: offset}
+			switch r {
+			case '+', '-', '/', '^':
+				it.value = string(r)
+			case '*':
+				it.value = "*"
+				if next(offset+1) == '*' {
+					it.value = "^"
+					size++
+				}
+			case '·', '⋅', '×':
+				it.value = "*"
+			case '−':
+				it.value = "-"
+			case '÷':
+				it.value = "/"
+			case '(':
+				it.kind, it.value = itemLeft, "("
+			case ')':
+				it.kind, it.value = itemRight, ")"
+			case ',':
+				it.kind, it.value = itemComma, ","
+			default:
+				return items, syntax(offset, fmt.Sprintf("not valid symbol `%c`", r))
+			}
+			offset += size
+			it.end = offset
+			items = append(items, it)
+		}
+	}
+	return
+}
+
+type nodeKind int
+
+const (
+	numberNode nodeKind = iota
+	nameNode
+	unaryNode  // value is operator, args is operand
+	binaryNode // value is operator, args are left and right operands
+	callNode   // value is name of function
+	parenNode
+)
+
+// node of input expression tree
+type node struct {
+	kind   nodeKind
+	value  string
+	offset int // byte offset in input expression
+	args   []*node
+}
+
+// input is parser of input expression. Grammar:
+//
+//	summ    = term { ( "+" | "-" ) term }
+//	term    = unary { ( "*" | "/" ) unary | power }
+//	unary   = ( "+" | "-" ) unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | name [ "(" [ summ { "," summ } ] ")" ] | "(" summ ")"
+//
+// Power is right-associative and have precedence higher then unary
+// operations, for example `-x^2` is `-pow(x,2)`. Operand after operand
+// without operator is multiplication, for example `2x` is `2*x`.
+// Function call is name with paren without spaces between them, so
+// that `x(a+b)` is call, but `x (a+b)` is `x*(a+b)`. Internal and
+// declared functions are calls with spaces, for example `sin (x)`. Call
+// of name, that is not function, is replaced by multiplication after
+// parsing of declarations.
+type input struct {
+	expr  string
+	items []item
+	pos   int
+	calls map[string]bool // functions with spaces before paren
+}
+
+// functions return names of internal functions, declarations and
+// functions `fs`
+func functions(fs ...string) map[string]bool {
+	calls := map[string]bool{"function": true, "constant": true, "variable": true}
+	for _, name := range internalNames() {
+		calls[name] = true
+	}
+	for _, name := range fs {
+		calls[name] = true
+	}
+	return calls
+}
+
+// parseInput return tree of input expression. Names in `calls` are
+// functions with spaces before paren.
+func parseInput(expr string, calls map[string]bool) (n *node, err *SyntaxError) {
+	items, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	in := input{expr: expr, items: items, calls: calls}
+	if len(items) == 0 {
+		return nil, in.syntax("empty expression")
+	}
+	if n, err = in.summ(); err != nil {
+		return nil, err
+	}
+	if in.pos < len(in.items) {
+		return nil, in.syntax("unexpected " + in.items[in.pos].String())
+	}
+	return n, nil
+}
+
+// syntax return error at current item
+func (in *input) syntax(msg string) *SyntaxError {
+	offset := len(in.expr)
+	if in.pos < len(in.items) {
+		offset = in.items[in.pos].offset
+	}
+	return &SyntaxError{
+		Location: Location{Expr: in.expr, Column: offset + 1},
+		Msg:      msg,
+	}
+}
+
+// is return true if current item is kind with one of values
+func (in *input) is(kind itemKind, values ...string) bool {
+	if len(in.items) <= in.pos || in.items[in.pos].kind != kind {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if in.items[in.pos].value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *input) summ() (n *node, err *SyntaxError) {
+	if n, err = in.term(); err != nil {
+		return
+	}
+	for in.is(itemOperator, "+", "-") {
+		op := in.items[in.pos]
+		in.pos++
+		y, err := in.term()
+		if err != nil {
+			return nil, err
+		}
+		n = &node{kind: binaryNode, value: op.value, offset: op.offset, args: []*node{n, y}}
+	}
+	return
+}
+
+func (in *input) term() (n *node, err *SyntaxError) {
+	if n, err = in.unary(); err != nil {
+		return
+	}
+	for {
+		var y *node
+		op := item{value: "*"}
+		switch {
+		case in.is(itemOperator, "*", "/"):
+			op = in.items[in.pos]
+			in.pos++
+			y, err = in.unary()
+		case in.is(itemNumber) || in.is(itemName) || in.is(itemLeft):
+			// implicit multiplication
+			op.offset = in.items[in.pos].offset
+			y, err = in.power()
+		default:
+			return
+		}
+		if err != nil {
+			return nil, err
+		}
+		n = &node{kind: binaryNode, value: op.value, offset: op.offset, args: []*node{n, y}}
+	}
+}
+
+func (in *input) unary() (n *node, err *SyntaxError) {
+	if !in.is(itemOperator, "+", "-") {
+		return in.power()
+	}
+	op := in.items[in.pos]
+	in.pos++
+	x, err := in.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: unaryNode, value: op.value, offset: op.offset, args: []*node{x}}, nil
+}
+
+func (in *input) power() (n *node, err *SyntaxError) {
+	if n, err = in.primary(); err != nil {
+		return
+	}
+	if !in.is(itemOperator, "^") {
+		return
+	}
+	op := in.items[in.pos]
+	in.pos++
+	y, err := in.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: binaryNode, value: op.value, offset: op.offset, args: []*node{n, y}}, nil
+}
+
+func (in *input) primary() (n *node, err *SyntaxError) {
+	if len(in.items) <= in.pos {
+		return nil, in.syntax("operand is not found")
+	}
+	it := in.items[in.pos]
+	switch it.kind {
+	case itemNumber:
+		in.pos++
+		return &node{kind: numberNode, value: it.value, offset: it.offset}, nil
+
+	case itemName:
+		in.pos++
+		if !in.is(itemLeft) || (in.items[in.pos].offset != it.end && !in.calls[it.value]) {
+			return &node{kind: nameNode, value: it.value, offset: it.offset}, nil
+		}
+		// function call
+		in.pos++
+		n = &node{kind: callNode, value: it.value, offset: it.offset}
+		for !in.is(itemRight) {
+			if 0 < len(n.args) {
+				if !in.is(itemComma) {
+					return nil, in.syntax("expected `,` or `)`")
+				}
+				in.pos++
+			}
+			arg, err := in.summ()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, arg)
+		}
+		in.pos++
+		return n, nil
+
+	case itemLeft:
+		in.pos++
+		x, err := in.summ()
+		if err != nil {
+			return nil, err
+		}
+		if !in.is(itemRight) {
+			return nil, in.syntax("expected `)`")
+		}
+		in.pos++
+		return &node{kind: parenNode, offset: it.offset, args: []*node{x}}, nil
+	}
+	return nil, in.syntax("unexpected " + it.String())
+}
+
+// toAst convert tree of input expression to Go tree. Position of Go
+// node is byte offset of node in input expression plus one, so that it
+// is column of node.
+func (n *node) toAst() (goast.Expr, error) {
+	args := make([]goast.Expr, len(n.args))
+	for i := range n.args {
+		arg, err := n.args[i].toAst()
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+	pos := token.Pos(n.offset + 1)
+	switch n.kind {
+	case numberNode:
+		lit := &goast.BasicLit{ValuePos: pos, Kind: token.FLOAT, Value: n.value}
+		if strings.IndexFunc(n.value, func(r rune) bool {
+			return r < '0' || '9' < r
+		}) < 0 {
+			lit.Kind = token.INT
+		}
+		return lit, nil
+
+	case nameNode:
+		return &goast.Ident{NamePos: pos, Name: n.value}, nil
+
+	case unaryNode:
+		op := token.SUB
+		if n.value == "+" {
+			op = token.ADD
+		}
+		return &goast.UnaryExpr{OpPos: pos, Op: op, X: args[0]}, nil
+
+	case binaryNode:
+		x, y := args[0], args[1]
+		if n.value == "^" {
+			return &goast.CallExpr{
+				Fun:  &goast.Ident{NamePos: x.Pos(), Name: pow},
+				Args: []goast.Expr{x, y},
+			}, nil
+		}
+		var op token.Token
+		switch n.value {
+		case "+":
+			op = token.ADD
+		case "-":
+			op = token.SUB
+		case "*":
+			op = token.MUL
+		case "/":
+			op = token.QUO
+		}
+		return &goast.BinaryExpr{X: x, OpPos: pos, Op: op, Y: y}, nil
+
+	case callNode:
+		return &goast.CallExpr{Fun: &goast.Ident{NamePos: pos, Name: n.value}, Args: args}, nil
+
+	case parenNode:
+		return &goast.ParenExpr{Lparen: pos, X: args[0]}, nil
+	}
+	return nil, &UnsupportedError{
+		Location: Location{Expr: n.value, Column: int(pos)},
+		Msg:      fmt.Sprintf("not valid node kind %d", n.kind),
+	}
+}
+
+// parseExpr return Go tree of input expression
+func parseExpr(expr string) (goast.Expr, error) {
+	n, err := parseInput(expr, functions())
+	if err != nil {
+		return nil, err
+	}
+	return n.toAst()
+}
@@ -0,0 +1,76 @@
+package sm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInput(t *testing.T) {
+	for i, tc := range []struct {
+		expr string
+		out  string
+	}{
+		{"x^2", "pow(x,2)"},
+		{"x**2", "pow(x,2)"},
+		{"-x^2", "-pow(x,2)"},
+		{"a^b^c", "pow(a,pow(b,c))"},
+		{"a**b**c", "pow(a,pow(b,c))"},
+		{"2*x^-1/a", "2*pow(x,-1)/a"},
+		{"(a+b)^(n-1)", "pow((a+b),(n-1))"},
+		{"sin(x)^2+d(u,x)^2", "pow(sin(x),2)+pow(d(u,x),2)"},
+		{"x^sin(x)^2", "pow(x,pow(sin(x),2))"},
+		{"a*b", "a*b"},
+		{"2x", "2*x"},
+		{"2x^2", "2*pow(x,2)"},
+		{"3(a+b)", "3*(a+b)"},
+		{"(a+b)(a-b)", "(a+b)*(a-b)"},
+		{"a b/c", "a*b/c"},
+		{"2x (x+1)", "2*x*(x+1)"},
+		{"1.5e-3x", "1.5e-3*x"},
+		{"2e", "2*e"},
+		{".5E+2", ".5E+2"},
+		{"λ·x₁ − E_1×x_{12}", "λ*x_1-E_1*x_12"},
+		{"u'' + u'", "uʹʹ+uʹ"},
+		{"f()", "f()"},
+		{"pow (a,2)", "pow(a,2)"},
+		{"sin (x)*2", "sin(x)*2"},
+		{"d (x*x,x)", "d(x*x,x)"},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			a, err := parseExpr(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := strings.Replace(astToStr(a), " ", "", -1); act != tc.out {
+				t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, tc.out)
+			}
+		})
+	}
+	for i, tc := range []struct {
+		expr   string
+		column int
+	}{
+		{"^2", 1},
+		{"x^", 3},
+		{"x^*2", 3},
+		{"x^(2", 5},
+		{"(x^2", 5},
+		{"a + # b", 5},
+		{"1.2.3", 4},
+		{"x_{1", 2},
+		{"2*type", 3},
+		{"f(a b,)", 7},
+		{"", 1},
+	} {
+		t.Run(fmt.Sprintf("error%d:%s", i, tc.expr), func(t *testing.T) {
+			_, err := parseInput(tc.expr, functions())
+			if err == nil {
+				t.Fatalf("error is not found")
+			}
+			if err.Column != tc.column {
+				t.Fatalf("not valid column %d: %v", err.Column, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+package sm
+
+import (
+	"go/parser"
+	"go/token"
+	"math"
+	"math/big"
+	"strings"
+
+	goast "go/ast"
+)
+
+// callOf return call of function with arguments
+func callOf(name string, args ...goast.Expr) goast.Expr {
+	return &goast.CallExpr{Fun: goast.NewIdent(name), Args: args}
+}
+
+// binaryOf return binary expression with protected operands
+func binaryOf(x goast.Expr, op token.Token, y goast.Expr) goast.Expr {
+	protect := func(e goast.Expr) goast.Expr {
+		// negative numbers, for example `-1.000`
+		if lit, ok := e.(*goast.BasicLit); ok && strings.HasPrefix(lit.Value, "-") {
+			return &goast.ParenExpr{X: e}
+		}
+		return paren(e)
+	}
+	return &goast.BinaryExpr{X: protect(x), Op: op, Y: protect(y)}
+}
+
+// hasCall return true if expression contains call of function
+func hasCall(e goast.Expr, name string) (found bool) {
+	goast.Inspect(e, func(n goast.Node) bool {
+		if call, ok := n.(*goast.CallExpr); ok {
+			if id, ok := call.Fun.(*goast.Ident); ok && id.Name == name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return
+}
+
+// simplify return simplified expression
+func (s *sm) simplify(e goast.Expr) (r goast.Expr, err error) {
+	copy := s.copy()
+	copy.base = astToStr(e)
+	out, err := copy.run()
+	s.iter += copy.iter
+	if err != nil {
+		return nil, err
+	}
+	r, err = parser.ParseExpr(out)
+	if err != nil {
+		return nil, s.errorGen(err)
+	}
+	return r, nil
+}
+
+// singular return true, if definite integral with numeric bounds cannot
+// be calculated by antiderivative, because integrand is not finite
+// between bounds, for example `integral(1/x,x,-1,1)`. Poles are roots of
+// polynomial denominator. Integrand must be real value between bounds
+// and antiderivative must be finite on bounds. Antiderivative is nil, if
+// it is not found.
+func (s *sm) singular(function, antiderivative, variable, a, b goast.Expr) (bool, error) {
+	okA, va := isNumber(a)
+	okB, vb := isNumber(b)
+	if !okA || !okB {
+		return false, nil
+	}
+	if vb < va {
+		va, vb = vb, va
+	}
+	name := astToStr(variable)
+
+	// amount of points for checking values between bounds
+	const points = 100
+
+	// poles
+	var do quoArray
+	for _, f := range s.parseQuoArray(function).do {
+		if !s.independent(f, name) {
+			do.up = append(do.up, f)
+		}
+	}
+	if 0 < len(do.up) {
+		p, ok, err := s.polyOf(s.quoToAst(do), name)
+		if err != nil {
+			return false, err
+		}
+		if ok && len(p.vars()) <= 1 {
+			roots, rest := p.roots(name)
+			for _, r := range roots {
+				if v, _ := r.value.Float64(); va <= v && v <= vb {
+					return true, nil
+				}
+			}
+			// change of sign of polynomial without rational roots
+			var last *big.Rat
+			for i := 0; i <= points && 0 < rest.degreeOf(name); i++ {
+				x := new(big.Rat).SetFloat64(va + (vb-va)*float64(i)/points)
+				v, _ := rest.evaluate(map[string]*big.Rat{name: x})
+				if v.Sign() == 0 || (last != nil && v.Sign() != last.Sign()) {
+					return true, nil
+				}
+				last = v
+			}
+		}
+	}
+
+	// integrand is not real between bounds, for example `log(x)` for
+	// negative values
+	for i := 0; i <= points; i++ {
+		x := va + (vb-va)*float64(i)/points
+		v, err := s.evaluate(function, map[string]float64{external(name): x})
+		if err != nil {
+			// integrand with other names
+			break
+		}
+		if math.IsNaN(v) {
+			return true, nil
+		}
+	}
+
+	// pole of antiderivative on bounds
+	if antiderivative == nil {
+		return false, nil
+	}
+	for _, bound := range []float64{va, vb} {
+		v, err := s.evaluate(antiderivative, map[string]float64{external(name): bound})
+		if err != nil {
+			// antiderivative with other names
+			return false, nil
+		}
+		if math.IsInf(v, 0) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// difference return difference of values with folded signs of negative
+// numbers
+//
+//	-cos(a) - -1.000
+//	-cos(a) + 1.000
+func (s *sm) difference(upper, lower goast.Expr) goast.Expr {
+	summ := parseSummArray(upper)
+	for _, part := range parseSummArray(lower) {
+		part.isNegative = !part.isNegative
+		summ = append(summ, part)
+	}
+	for i := range summ {
+		if ok, v := isRational(summ[i].value); ok && v.Sign() < 0 {
+			summ[i].isNegative = !summ[i].isNegative
+			summ[i].value = s.createRat(v.Neg(v))
+		}
+	}
+	return summ.toAst()
+}
+
+// antiderivative return simplified antiderivative of function by
+// variable. Result is not ok, if antiderivative is not found.
+func (s *sm) antiderivative(function, variable goast.Expr) (r goast.Expr, ok bool, err error) {
+	r, err = s.simplify(callOf(integralName, function, variable))
+	if err != nil {
+		return nil, false, err
+	}
+	if hasCall(r, integralName) {
+		return nil, false, nil
+	}
+	return r, true, nil
+}
+
+// integrate return antiderivative of function without constant factors
+// by variable. Result may contain integrals for next steps of
+// simplification, for example after integration by parts.
+//
+//	integral(a, x)           = a*x
+//	integral(pow(x,n), x)    = pow(x,n+1)/(n+1)
+//	integral(1/x, x)         = log(abs(x))
+//	integral(sin(a*x+b), x)  = -cos(a*x+b)/a
+//	integral(pow(c,x), x)    = pow(c,x)/log(c)
+//	integral(x*exp(x), x)    = x*exp(x) - integral(exp(x), x)
+func (s *sm) integrate(function, variable goast.Expr) (r goast.Expr, ok bool) {
+	name := astToStr(variable)
+	if s.independent(function, name) {
+		return binaryOf(function, token.MUL, variable), true
+	}
+	ps, ok := s.powers(s.parseQuoArray(function), name)
+	if !ok {
+		return nil, false
+	}
+	switch len(ps) {
+	case 1:
+		return s.integratePower(ps[0], name)
+	case 2:
+		return s.integrateByParts(ps, variable)
+	}
+	return nil, false
+}
+
+// power is factor `pow(base,exp)` of product
+type power struct {
+	base, exp goast.Expr
+}
+
+// powers return factors of product grouped by base. Factors of
+// denominator have negative exponents. Result is not ok for factors
+// independent of variable.
+func (s *sm) powers(q quoArray, name string) (ps []power, ok bool) {
+	var (
+		bases []string
+		exps  = map[string][]goast.Expr{}
+		group = map[string]goast.Expr{}
+	)
+	add := func(e goast.Expr, negative bool) bool {
+		if ok, v := isNumber(e); ok && v == 1 {
+			return true
+		}
+		if s.independent(e, name) {
+			return false
+		}
+		base, exp := unparen(e), goast.Expr(s.createFloat(1))
+		if call, ok := base.(*goast.CallExpr); ok && len(call.Args) == 2 && astToStr(call.Fun) == pow {
+			base, exp = unparen(call.Args[0]), call.Args[1]
+		}
+		if call, ok := base.(*goast.CallExpr); ok && len(call.Args) == 1 && astToStr(call.Fun) == sqrtName {
+			base, exp = unparen(call.Args[0]), s.createFloat(0.5)
+		}
+		if negative {
+			exp = s.negative(exp)
+		}
+		key := astToStr(base)
+		if _, ok := group[key]; !ok {
+			bases = append(bases, key)
+			group[key] = base
+		}
+		exps[key] = append(exps[key], exp)
+		return true
+	}
+	for i := range q.up {
+		if !add(q.up[i], false) {
+			return nil, false
+		}
+	}
+	for i := range q.do {
+		if !add(q.do[i], true) {
+			return nil, false
+		}
+	}
+	for _, key := range bases {
+		exp := s.summExps(exps[key])
+		if ok, v := isNumber(exp); ok && v == 0 {
+			continue
+		}
+		ps = append(ps, power{base: group[key], exp: exp})
+	}
+	return ps, true
+}
+
+// negative return negative exponent
+func (s *sm) negative(exp goast.Expr) goast.Expr {
+	if ok, v := isRational(exp); ok {
+		return s.createRat(v.Neg(v))
+	}
+	return &goast.UnaryExpr{Op: token.SUB, X: paren(exp)}
+}
+
+// summExps return summ of exponents
+func (s *sm) summExps(exps []goast.Expr) (r goast.Expr) {
+	sum, exact := new(big.Rat), true
+	for i := range exps {
+		if ok, v := isRational(exps[i]); ok {
+			sum.Add(sum, v)
+			continue
+		}
+		exact = false
+	}
+	if exact {
+		return s.createRat(sum)
+	}
+	for i := range exps {
+		if i == 0 {
+			r = exps[i]
+			continue
+		}
+		r = binaryOf(r, token.ADD, exps[i])
+	}
+	return
+}
+
+// integratePower return antiderivative of one factor
+func (s *sm) integratePower(p power, name string) (r goast.Expr, ok bool) {
+	if !s.independent(p.exp, name) {
+		// integral(pow(c, a*x+b), x) = pow(c, a*x+b)/(log(c)*a)
+		if !s.independent(p.base, name) {
+			return nil, false
+		}
+		a, _, ok := s.linear(p.exp, name)
+		if !ok {
+			return nil, false
+		}
+		return binaryOf(
+			callOf(pow, p.base, p.exp),
+			token.QUO,
+			s.coefficient(callOf(logName, p.base), a),
+		), true
+	}
+
+	// integral(pow(a*x+b, n), x) = pow(a*x+b, n+1)/((n+1)*a)
+	// integral(1/(a*x+b), x) = log(abs(a*x+b))/a
+	if a, _, ok := s.linear(p.base, name); ok {
+		if ok, v := isNumber(p.exp); ok && v == -1 {
+			return s.quo(callOf(logName, callOf(absName, p.base)), a), true
+		}
+		exp := s.summExps([]goast.Expr{p.exp, s.createFloat(1)})
+		return binaryOf(
+			callOf(pow, p.base, exp),
+			token.QUO,
+			s.coefficient(exp, a),
+		), true
+	}
+
+	// integral(sin(a*x+b), x) = -cos(a*x+b)/a
+	if ok, v := isNumber(p.exp); !ok || v != 1 {
+		return nil, false
+	}
+	call, ok := p.base.(*goast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, false
+	}
+	a, _, ok := s.linear(call.Args[0], name)
+	if !ok {
+		return nil, false
+	}
+	f, ok := s.primitive(astToStr(call.Fun), call.Args[0])
+	if !ok {
+		return nil, false
+	}
+	return s.quo(f, a), true
+}
+
+// integrateByParts return antiderivative of product polynomial and
+// function by integration by parts
+//
+//	integral(pow(x,k)*g, x) = pow(x,k)*G - k*integral(pow(x,k-1)*G, x)
+//	integral(pow(x,k)*log(a*x), x) = pow(x,k+1)/(k+1)*log(a*x) - pow(x,k+1)/((k+1)*(k+1))
+func (s *sm) integrateByParts(ps []power, variable goast.Expr) (r goast.Expr, ok bool) {
+	name := astToStr(variable)
+	if id, ok := ps[1].base.(*goast.Ident); ok && id.Name == name {
+		ps[0], ps[1] = ps[1], ps[0]
+	}
+	if id, ok := ps[0].base.(*goast.Ident); !ok || id.Name != name {
+		return nil, false
+	}
+	ok, k := isRational(ps[0].exp)
+	if !ok {
+		return nil, false
+	}
+	g := ps[1]
+	call, isCall := g.base.(*goast.CallExpr)
+	if isCall && len(call.Args) == 1 && astToStr(call.Fun) == logName {
+		if ok, v := isNumber(g.exp); !ok || v != 1 {
+			return nil, false
+		}
+		if _, b, ok := s.linear(call.Args[0], name); !ok || b != nil {
+			return nil, false
+		}
+		if k.Cmp(big.NewRat(-1, 1)) == 0 {
+			// integral(log(a*x)/x, x) = log(a*x)*log(a*x)/2
+			return binaryOf(
+				binaryOf(call, token.MUL, call),
+				token.QUO,
+				s.createFloat(2),
+			), true
+		}
+		exp := s.createRat(new(big.Rat).Add(k, big.NewRat(1, 1)))
+		p := binaryOf(callOf(pow, variable, exp), token.QUO, exp)
+		return binaryOf(
+			binaryOf(p, token.MUL, call),
+			token.SUB,
+			binaryOf(p, token.QUO, exp),
+		), true
+	}
+
+	// polynomial part is decreased by each integration
+	if !k.IsInt() || k.Sign() <= 0 {
+		return nil, false
+	}
+	if s.independent(g.exp, name) {
+		if !isCall || len(call.Args) != 1 {
+			return nil, false
+		}
+		switch astToStr(call.Fun) {
+		case sinName, cosName, expName, sinhName, coshName:
+		default:
+			return nil, false
+		}
+	}
+	G, ok := s.integratePower(g, name)
+	if !ok {
+		return nil, false
+	}
+	rest := goast.Expr(G)
+	if lower := new(big.Rat).Sub(k, big.NewRat(1, 1)); lower.Sign() != 0 {
+		rest = binaryOf(callOf(pow, variable, s.createRat(lower)), token.MUL, G)
+	}
+	return binaryOf(
+		binaryOf(callOf(pow, variable, s.createRat(k)), token.MUL, G),
+		token.SUB,
+		binaryOf(s.createRat(k), token.MUL, callOf(integralName, rest, variable)),
+	), true
+}
+
+// primitive return antiderivative of elementary function by argument `u`
+func (s *sm) primitive(name string, u goast.Expr) (r goast.Expr, ok bool) {
+	one := func() goast.Expr {
+		// 1-u*u
+		return binaryOf(s.createFloat(1), token.SUB, binaryOf(u, token.MUL, u))
+	}
+	switch name {
+	case sinName:
+		// -cos(u)
+		return &goast.UnaryExpr{Op: token.SUB, X: callOf(cosName, u)}, true
+	case cosName:
+		// sin(u)
+		return callOf(sinName, u), true
+	case tanName:
+		// -log(abs(cos(u)))
+		return &goast.UnaryExpr{Op: token.SUB, X: callOf(logName, callOf(absName, callOf(cosName, u)))}, true
+	case expName:
+		// exp(u)
+		return callOf(expName, u), true
+	case logName:
+		// u*log(u)-u
+		return binaryOf(binaryOf(u, token.MUL, callOf(logName, u)), token.SUB, u), true
+	case asinName:
+		// u*asin(u)+sqrt(1-u*u)
+		return binaryOf(binaryOf(u, token.MUL, callOf(asinName, u)), token.ADD, callOf(sqrtName, one())), true
+	case acosName:
+		// u*acos(u)-sqrt(1-u*u)
+		return binaryOf(binaryOf(u, token.MUL, callOf(acosName, u)), token.SUB, callOf(sqrtName, one())), true
+	case atanName:
+		// u*atan(u)-log(1+u*u)/2
+		return binaryOf(
+			binaryOf(u, token.MUL, callOf(atanName, u)),
+			token.SUB,
+			binaryOf(
+				callOf(logName, binaryOf(s.createFloat(1), token.ADD, binaryOf(u, token.MUL, u))),
+				token.QUO,
+				s.createFloat(2),
+			),
+		), true
+	case sinhName:
+		// cosh(u)
+		return callOf(coshName, u), true
+	case coshName:
+		// sinh(u)
+		return callOf(sinhName, u), true
+	case tanhName:
+		// log(cosh(u))
+		return callOf(logName, callOf(coshName, u)), true
+	}
+	return nil, false
+}
+
+// linear return coefficients of linear function `a*x+b` by variable.
+// Coefficient `b` is nil for function `a*x`.
+func (s *sm) linear(u goast.Expr, name string) (a, b goast.Expr, ok bool) {
+	add := func(summ goast.Expr, part sliceSumm) goast.Expr {
+		if summ == nil {
+			return part.toAst()
+		}
+		if part.isNegative {
+			return binaryOf(summ, token.SUB, part.value)
+		}
+		return binaryOf(summ, token.ADD, part.value)
+	}
+	for _, part := range parseSummArray(u) {
+		if s.independent(part.value, name) {
+			b = add(b, part)
+			continue
+		}
+		q := s.parseQuoArray(part.value)
+		found := -1
+		for i := range q.up {
+			if id, ok := unparen(q.up[i]).(*goast.Ident); ok && id.Name == name {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			return nil, nil, false
+		}
+		q.up = append(q.up[:found:found], q.up[found+1:]...)
+		coeff := s.quoToAst(q)
+		if !s.independent(coeff, name) {
+			return nil, nil, false
+		}
+		a = add(a, sliceSumm{isNegative: part.isNegative, value: coeff})
+	}
+	return a, b, a != nil
+}
+
+// quo return expression divided by coefficient
+func (s *sm) quo(e, coeff goast.Expr) goast.Expr {
+	if ok, v := isNumber(coeff); ok && v == 1 {
+		return e
+	}
+	return binaryOf(e, token.QUO, coeff)
+}
+
+// coefficient return product of factor and coefficient
+func (s *sm) coefficient(factor, coeff goast.Expr) goast.Expr {
+	if ok, v := isNumber(coeff); ok && v == 1 {
+		return factor
+	}
+	return binaryOf(factor, token.MUL, coeff)
+}
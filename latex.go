@@ -0,0 +1,263 @@
+package sm
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"unicode"
+
+	goast "go/ast"
+)
+
+// LaTeX return expression in LaTeX format. Expression is in Sexpr syntax,
+// for example result of simplification.
+// Example:
+//
+//	expr : "2.000*(a*pow(x,a - 1.000))"
+//	out  : "2.000 \cdot a \cdot {x}^{a - 1.000}"
+//
+//	expr : "matrix(1,2,3,4,2,2)"
+//	out  : "\begin{pmatrix} 1 & 2 \\ 3 & 4 \end{pmatrix}"
+func LaTeX(expr string) (out string, err error) {
+	a, err := parseExpr(expr)
+	if err != nil {
+		return "", err
+	}
+	return latex(a), nil
+}
+
+// LaTeX return expression in LaTeX format.
+func (e Expr) LaTeX() string {
+	if e.ast == nil {
+		return ""
+	}
+	return latex(e.ast)
+}
+
+// unparen return expression without parens
+func unparen(e goast.Expr) goast.Expr {
+	for {
+		p, ok := e.(*goast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
+
+// isSumm return true for expressions `a + b` and `a - b`
+func isSumm(e goast.Expr) bool {
+	bin, ok := unparen(e).(*goast.BinaryExpr)
+	return ok && (bin.Op == token.ADD || bin.Op == token.SUB)
+}
+
+// isNegative return true for expression `-a`
+func isNegative(e goast.Expr) bool {
+	un, ok := unparen(e).(*goast.UnaryExpr)
+	return ok && un.Op == token.SUB
+}
+
+// latexParen return expression in LaTeX format with parens
+func latexParen(e goast.Expr, paren bool) string {
+	if paren {
+		return `\left(` + latex(e) + `\right)`
+	}
+	return latex(e)
+}
+
+func latex(e goast.Expr) string {
+	switch v := unparen(e).(type) {
+	case *goast.BasicLit:
+		return latexNumber(v)
+
+	case *goast.Ident:
+		return latexName(external(v.Name))
+
+	case *goast.UnaryExpr:
+		return v.Op.String() + latexParen(v.X, isSumm(v.X) || isNegative(v.X))
+
+	case *goast.BinaryExpr:
+		switch v.Op {
+		case token.ADD, token.SUB:
+			y := latexParen(v.Y, isNegative(v.Y) || (v.Op == token.SUB && isSumm(v.Y)))
+			return latex(v.X) + " " + v.Op.String() + " " + y
+		case token.MUL:
+			x := latexParen(v.X, isSumm(v.X))
+			y := latexParen(v.Y, isSumm(v.Y) || isNegative(v.Y))
+			return x + ` \cdot ` + y
+		case token.QUO:
+			return `\frac{` + latex(v.X) + `}{` + latex(v.Y) + `}`
+		}
+
+	case *goast.CallExpr:
+		if id, ok := v.Fun.(*goast.Ident); ok {
+			if out, ok := latexCall(id.Name, v.Args); ok {
+				return out
+			}
+		}
+		var args []string
+		for i := range v.Args {
+			args = append(args, latex(v.Args[i]))
+		}
+		return `\operatorname{` + latex(v.Fun) + `}\left(` +
+			strings.Join(args, ", ") + `\right)`
+	}
+	return astToStr(e)
+}
+
+// latexNumber return number with exponent of scientific notation as
+// power of ten
+//
+//	1.5e-05 : 1.5 \cdot 10^{-5}
+func latexNumber(v *goast.BasicLit) string {
+	if v.Kind != token.FLOAT || strings.HasPrefix(strings.ToLower(v.Value), "0x") {
+		return v.Value
+	}
+	index := strings.IndexAny(v.Value, "eE")
+	if index < 0 {
+		return v.Value
+	}
+	mantissa, exponent := v.Value[:index], v.Value[index+1:]
+	sign := ""
+	if strings.HasPrefix(exponent, "-") {
+		sign = "-"
+	}
+	exponent = strings.TrimLeft(exponent, "+-0")
+	if exponent == "" {
+		return mantissa
+	}
+	return mantissa + ` \cdot 10^{` + sign + exponent + `}`
+}
+
+// greek is LaTeX macros of greek letters
+var greek = map[rune]string{
+	'α': `\alpha`, 'β': `\beta`, 'γ': `\gamma`, 'δ': `\delta`,
+	'ε': `\epsilon`, 'ζ': `\zeta`, 'η': `\eta`, 'θ': `\theta`,
+	'ι': `\iota`, 'κ': `\kappa`, 'λ': `\lambda`, 'μ': `\mu`,
+	'ν': `\nu`, 'ξ': `\xi`, 'π': `\pi`, 'ρ': `\rho`,
+	'σ': `\sigma`, 'ς': `\varsigma`, 'τ': `\tau`, 'υ': `\upsilon`,
+	'φ': `\phi`, 'χ': `\chi`, 'ψ': `\psi`, 'ω': `\omega`,
+	'Γ': `\Gamma`, 'Δ': `\Delta`, 'Θ': `\Theta`, 'Λ': `\Lambda`,
+	'Ξ': `\Xi`, 'Π': `\Pi`, 'Σ': `\Sigma`, 'Υ': `\Upsilon`,
+	'Φ': `\Phi`, 'Ψ': `\Psi`, 'Ω': `\Omega`,
+}
+
+// latexName return name with greek letters as macros and subscript
+// after first symbol `_`
+//
+//	x_1   : x_{1}
+//	α_ij  : \alpha_{ij}
+func latexName(name string) string {
+	letters := func(s string) string {
+		var out strings.Builder
+		rs := []rune(s)
+		for i, r := range rs {
+			macro, ok := greek[r]
+			if !ok {
+				out.WriteRune(r)
+				continue
+			}
+			out.WriteString(macro)
+			if i+1 < len(rs) && unicode.IsLetter(rs[i+1]) {
+				out.WriteString(" ")
+			}
+		}
+		return strings.Replace(out.String(), "_", `\_`, -1)
+	}
+	index := strings.Index(name, "_")
+	if index <= 0 || index == len(name)-1 {
+		return letters(name)
+	}
+	return letters(name[:index]) + "_{" + letters(name[index+1:]) + "}"
+}
+
+// latexBase return base of power or operand of postfix operations
+func latexBase(e goast.Expr) string {
+	switch v := unparen(e).(type) {
+	case *goast.Ident:
+		return latex(v)
+	case *goast.BasicLit:
+		return latex(v)
+	case *goast.CallExpr:
+		if id, ok := v.Fun.(*goast.Ident); ok && id.Name == matrix {
+			return latex(v)
+		}
+	}
+	return latexParen(e, true)
+}
+
+func latexCall(name string, args []goast.Expr) (out string, ok bool) {
+	switch {
+	case name == pow && len(args) == 2:
+		return `{` + latexBase(args[0]) + `}^{` + latex(args[1]) + `}`, true
+
+	case name == matrix && 2 < len(args):
+		okr, rows := isNumber(args[len(args)-2])
+		okc, cols := isNumber(args[len(args)-1])
+		r, c := int(rows), int(cols)
+		if !okr || !okc || r <= 0 || c <= 0 || r*c != len(args)-2 {
+			return "", false
+		}
+		var lines []string
+		for i := 0; i < r; i++ {
+			var line []string
+			for j := 0; j < c; j++ {
+				line = append(line, latex(args[i*c+j]))
+			}
+			lines = append(lines, strings.Join(line, " & "))
+		}
+		return `\begin{pmatrix} ` + strings.Join(lines, ` \\ `) +
+			` \end{pmatrix}`, true
+
+	case name == differential && len(args) == 3:
+		if _, ok := unparen(args[0]).(*goast.Ident); ok {
+			return fmt.Sprintf(`\frac{d^{%s}%s}{d%s^{%s}}`, latex(args[2]),
+				latex(args[0]), latex(args[1]), latex(args[2])), true
+		}
+		return fmt.Sprintf(`\frac{d^{%s}}{d%s^{%s}}\left(%s\right)`, latex(args[2]),
+			latex(args[1]), latex(args[2]), latex(args[0])), true
+
+	case name == differential && len(args) == 2:
+		if _, ok := unparen(args[0]).(*goast.Ident); ok {
+			return fmt.Sprintf(`\frac{d%s}{d%s}`, latex(args[0]), latex(args[1])), true
+		}
+		return fmt.Sprintf(`\frac{d}{d%s}\left(%s\right)`,
+			latex(args[1]), latex(args[0])), true
+
+	case name == integralName && len(args) == 4:
+		return fmt.Sprintf(`\int_{%s}^{%s} %s \, d%s`,
+			latex(args[2]), latex(args[3]),
+			latexParen(args[0], isSumm(args[0])), latex(args[1])), true
+
+	case name == integralName && len(args) == 2:
+		return fmt.Sprintf(`\int %s \, d%s`,
+			latexParen(args[0], isSumm(args[0])), latex(args[1])), true
+
+	case name == injectName && len(args) == 3:
+		return fmt.Sprintf(`\left. %s \right|_{%s = %s}`,
+			latex(args[0]), latex(args[1]), latex(args[2])), true
+
+	case name == transpose && len(args) == 1:
+		return `{` + latexBase(args[0]) + `}^{T}`, true
+
+	case name == inverse && len(args) == 1:
+		return `{` + latexBase(args[0]) + `}^{-1}`, true
+
+	case name == det && len(args) == 1:
+		return `\det ` + latexBase(args[0]), true
+
+	case name == sqrtName && len(args) == 1:
+		return `\sqrt{` + latex(args[0]) + `}`, true
+
+	case name == absName && len(args) == 1:
+		return `\left|` + latex(args[0]) + `\right|`, true
+
+	case isElementary(name) && len(args) == 1:
+		if strings.HasPrefix(name, "a") {
+			// asin, acos, atan
+			name = "arc" + name[1:]
+		}
+		return `\` + name + latexParen(args[0], true), true
+	}
+	return "", false
+}
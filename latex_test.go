@@ -0,0 +1,106 @@
+package sm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLaTeX(t *testing.T) {
+	for i, tc := range []struct {
+		expr string
+		out  string
+	}{
+		{
+			expr: "2.000*(a*pow(x,a - 1.000))",
+			out:  `2.000 \cdot a \cdot {x}^{a - 1.000}`,
+		},
+		{
+			expr: "(a+b)*(c-d)",
+			out:  `\left(a + b\right) \cdot \left(c - d\right)`,
+		},
+		{
+			expr: "a-(b-c)+(-d)",
+			out:  `a - \left(b - c\right) + \left(-d\right)`,
+		},
+		{
+			expr: "-(a+b)/(2*c)",
+			out:  `\frac{-\left(a + b\right)}{2 \cdot c}`,
+		},
+		{
+			expr: "(a/b)/c",
+			out:  `\frac{\frac{a}{b}}{c}`,
+		},
+		{
+			expr: "pow(a+1,2)*pow(pow(x,2),3)",
+			out:  `{\left(a + 1\right)}^{2} \cdot {\left({x}^{2}\right)}^{3}`,
+		},
+		{
+			expr: "log(abs(x-1))",
+			out:  `\log\left(\left|x - 1\right|\right)`,
+		},
+		{
+			expr: "matrix(1,2,3,4,2,2)",
+			out:  `\begin{pmatrix} 1 & 2 \\ 3 & 4 \end{pmatrix}`,
+		},
+		{
+			expr: "transpose(matrix(a,b,2,1))*inverse(m)*det(matrix(1,2,3,4,2,2))",
+			out: `{\begin{pmatrix} a \\ b \end{pmatrix}}^{T} \cdot {m}^{-1} \cdot ` +
+				`\det \begin{pmatrix} 1 & 2 \\ 3 & 4 \end{pmatrix}`,
+		},
+		{
+			expr: "d(u,x)+d(u*x,x)",
+			out:  `\frac{du}{dx} + \frac{d}{dx}\left(u \cdot x\right)`,
+		},
+		{
+			expr: "integral(x+1,x,0,L)",
+			out:  `\int_{0}^{L} \left(x + 1\right) \, dx`,
+		},
+		{
+			expr: "integral(x+1,x)+integral(sin(x),x)",
+			out:  `\int \left(x + 1\right) \, dx + \int \sin\left(x\right) \, dx`,
+		},
+		{
+			expr: "d(u,x,2)+d(u*x,x,3)",
+			out: `\frac{d^{2}u}{dx^{2}} + ` +
+				`\frac{d^{3}}{dx^{3}}\left(u \cdot x\right)`,
+		},
+		{
+			expr: "1.5e-05*x+2E+10+3e0",
+			out:  `1.5 \cdot 10^{-5} \cdot x + 2 \cdot 10^{10} + 3`,
+		},
+		{
+			expr: "inject(pow(x,2),x,a)+sin(x)+foo(x,y)",
+			out: `\left. {x}^{2} \right|_{x = a} + \sin\left(x\right) + ` +
+				`\operatorname{foo}\left(x, y\right)`,
+		},
+		{
+			expr: "sqrt(x)*exp(-x)+asin(x)+cosh(x)",
+			out: `\sqrt{x} \cdot \exp\left(-x\right) + \arcsin\left(x\right) + ` +
+				`\cosh\left(x\right)`,
+		},
+		{
+			expr: "1/3*q_1",
+			out:  `\frac{1}{3} \cdot q_{1}`,
+		},
+		{
+			expr: "λ*x_1 + α_{ij} + u' + λμ",
+			out:  `\lambda \cdot x_{1} + \alpha_{ij} + u' + \lambda \mu`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			act, err := LaTeX(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act != tc.out {
+				t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, tc.out)
+			}
+		})
+	}
+	t.Run("Expr", func(t *testing.T) {
+		e := Quo(Pow(Var("x"), Num(2)), Const("a"))
+		if act, exp := e.LaTeX(), `\frac{{x}^{2}}{a}`; act != exp {
+			t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, exp)
+		}
+	})
+}
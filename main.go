@@ -4,6 +4,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -11,12 +12,15 @@ import (
 )
 
 func main() {
-	if len(os.Args) == 0 {
+	cse := flag.Bool("cse", false, "output with common subexpression elimination")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "add expression")
 		return
 	}
 
-	expr := os.Args[1]
+	expr := flag.Arg(0)
 	fmt.Fprintf(os.Stdout, "expr = %s\n", expr)
 	var err error
 
@@ -27,5 +31,18 @@ func main() {
 			return
 		}
 	}
+
+	if *cse {
+		temps, result, err := sm.CSE(expr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			return
+		}
+		for _, t := range temps {
+			fmt.Fprintf(os.Stdout, "%s = %s\n", t.Name, t.Expr)
+		}
+		fmt.Fprintf(os.Stdout, "result = %s\n", result)
+		return
+	}
 	fmt.Fprintf(os.Stdout, "%s\n", expr)
 }
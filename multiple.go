@@ -0,0 +1,352 @@
+package sm
+
+import (
+	"fmt"
+	"go/token"
+	"math/big"
+
+	goast "go/ast"
+)
+
+// integralMultiple is double and triple integral. Bounds of inner
+// integral may depend on variables of outer integrals. Last argument is
+// optional name of quadrature scheme.
+//
+//	integral2(f, x, a, b, y, c, d)
+//	integral2(f, x, 0, 1, y, 0, 1-x, triangle3)
+//	integral3(f, x, a, b, y, c, d, z, e, g, gauss2)
+func (s *sm) integralMultiple(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	var dims int
+	switch id.Name {
+	case integral2:
+		dims = 2
+	case integral3:
+		dims = 3
+	default:
+		return false, nil, nil
+	}
+	if len(call.Args) != 1+3*dims && len(call.Args) != 2+3*dims {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     id.Name,
+			Args:     len(call.Args),
+			Expect:   1 + 3*dims,
+		})
+	}
+	function := call.Args[0]
+	var name []goast.Expr
+	if len(call.Args) == 2+3*dims {
+		name = call.Args[len(call.Args)-1:]
+		sc, err := s.scheme(call, name[0])
+		if err != nil {
+			return false, nil, err
+		}
+		if sc.triangle {
+			if dims != 2 {
+				return false, nil, s.errorGen(&UnsupportedError{
+					Location: Location{Expr: astToStr(call)},
+					Msg:      fmt.Sprintf("scheme `%s` is valid only for %s", astToStr(name[0]), integral2),
+				})
+			}
+			r, err := s.exactly(func(c *sm) (goast.Expr, error) {
+				return c.trianglePoints(call, sc, function, call.Args[1:7])
+			})
+			if err != nil {
+				return false, nil, err
+			}
+			return true, r, nil
+		}
+	}
+
+	// from:
+	// integral2(f, x, a, b, y, c, d)
+	// to:
+	// integral(integral(f, y, c, d), x, a, b)
+	for i := dims - 1; 0 <= i; i-- {
+		args := append([]goast.Expr{function}, call.Args[1+3*i:4+3*i]...)
+		function = callOf(integralName, append(args, name...)...)
+	}
+	return true, function, nil
+}
+
+// scheme is quadrature on reference domain. Points of line are in
+// range [-1,1], sum of weights is 2. Points of triangle are barycentric
+// coordinates, sum of weights is 1.
+type scheme struct {
+	points   [][]string
+	weights  []string
+	triangle bool
+}
+
+// schemes of quadrature by name
+var schemes = map[string]scheme{
+	"gauss1": {
+		points:  [][]string{{"0"}},
+		weights: []string{"2"},
+	},
+	"gauss2": {
+		points:  [][]string{{"-0.5773502691896257"}, {"0.5773502691896257"}},
+		weights: []string{"1", "1"},
+	},
+	"gauss3": {
+		points:  [][]string{{"-0.7745966692414834"}, {"0"}, {"0.7745966692414834"}},
+		weights: []string{"5/9", "8/9", "5/9"},
+	},
+	"gauss4": {
+		points: [][]string{
+			{"-0.8611363115940526"}, {"-0.3399810435848563"},
+			{"0.3399810435848563"}, {"0.8611363115940526"},
+		},
+		weights: []string{
+			"0.3478548451374538", "0.6521451548625461",
+			"0.6521451548625461", "0.3478548451374538",
+		},
+	},
+	"gauss5": {
+		points: [][]string{
+			{"-0.9061798459386640"}, {"-0.5384693101056831"}, {"0"},
+			{"0.5384693101056831"}, {"0.9061798459386640"},
+		},
+		weights: []string{
+			"0.2369268850561891", "0.4786286704993665", "0.5688888888888889",
+			"0.4786286704993665", "0.2369268850561891",
+		},
+	},
+	"triangle1": {
+		points:   [][]string{{"1/3", "1/3", "1/3"}},
+		weights:  []string{"1"},
+		triangle: true,
+	},
+	"triangle3": {
+		points: [][]string{
+			{"2/3", "1/6", "1/6"},
+			{"1/6", "2/3", "1/6"},
+			{"1/6", "1/6", "2/3"},
+		},
+		weights:  []string{"1/3", "1/3", "1/3"},
+		triangle: true,
+	},
+	"triangle4": {
+		points: [][]string{
+			{"1/3", "1/3", "1/3"},
+			{"0.6", "0.2", "0.2"},
+			{"0.2", "0.6", "0.2"},
+			{"0.2", "0.2", "0.6"},
+		},
+		weights:  []string{"-27/48", "25/48", "25/48", "25/48"},
+		triangle: true,
+	},
+	"triangle7": {
+		points: [][]string{
+			{"1/3", "1/3", "1/3"},
+			{"0.059715871789770", "0.470142064105115", "0.470142064105115"},
+			{"0.470142064105115", "0.059715871789770", "0.470142064105115"},
+			{"0.470142064105115", "0.470142064105115", "0.059715871789770"},
+			{"0.797426985353087", "0.101286507323456", "0.101286507323456"},
+			{"0.101286507323456", "0.797426985353087", "0.101286507323456"},
+			{"0.101286507323456", "0.101286507323456", "0.797426985353087"},
+		},
+		weights: []string{
+			"0.225",
+			"0.132394152788506", "0.132394152788506", "0.132394152788506",
+			"0.125939180544827", "0.125939180544827", "0.125939180544827",
+		},
+		triangle: true,
+	},
+}
+
+// schemeArg return index of argument with name of quadrature scheme.
+// Name of scheme is not constant and is not replaced by substitution.
+func schemeArg(call *goast.CallExpr) (index int, ok bool) {
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case id.Name == integralName && len(call.Args) == 5:
+	case id.Name == integral2 && len(call.Args) == 8:
+	case id.Name == integral3 && len(call.Args) == 11:
+	default:
+		return 0, false
+	}
+	index = len(call.Args) - 1
+	name, ok := unparen(call.Args[index]).(*goast.Ident)
+	if !ok {
+		return 0, false
+	}
+	_, ok = schemes[name.Name]
+	return index, ok
+}
+
+// scheme return quadrature scheme by name
+func (s *sm) scheme(call, name goast.Expr) (sc scheme, _ error) {
+	id, ok := unparen(name).(*goast.Ident)
+	if ok {
+		sc, ok = schemes[id.Name]
+	}
+	if !ok {
+		return sc, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      fmt.Sprintf("not valid name of quadrature scheme: %s", astToStr(name)),
+		})
+	}
+	return sc, nil
+}
+
+// exactly return result of quadrature simplified with exact numbers.
+// Rounding of points by FloatFormat adds wrong terms, for example
+// integral of `x*x*x` from `a` to `b` by `gauss2` have term
+// `0.002*(a*pow(b,3))`.
+func (s *sm) exactly(quadrature func(c *sm) (goast.Expr, error)) (goast.Expr, error) {
+	c := s.copy()
+	c.opts.Exact = true
+	r, err := quadrature(&c)
+	if err != nil {
+		return nil, err
+	}
+	r, err = c.simplify(r)
+	s.iter += c.iter
+	return r, err
+}
+
+// gaussPoints return integral by quadrature scheme on line
+//
+//	integral(f, x, a, b, gauss2) = (b-a)/2*(w1*f(x1) + w2*f(x2))
+//
+// where points are `x = (a+b)/2 + (b-a)/2*p`.
+func (s *sm) gaussPoints(sc scheme, function, variable, a, b goast.Expr) (goast.Expr, error) {
+	name := astToStr(variable)
+	center := s.addTerm(s.addTerm(nil, big.NewRat(1, 2), a), big.NewRat(1, 2), b)
+	half := s.addTerm(s.addTerm(nil, big.NewRat(1, 2), b), big.NewRat(-1, 2), a)
+	values := make([]goast.Expr, len(sc.points))
+	for i := range sc.points {
+		point, err := s.weightedSum([]string{"1", sc.points[i][0]}, []goast.Expr{center, half})
+		if err != nil {
+			return nil, err
+		}
+		values[i] = substitute(function, map[string]goast.Expr{name: point})
+	}
+	summ, err := s.weightedSum(sc.weights, values)
+	if err != nil {
+		return nil, err
+	}
+	return binaryOf(half, token.MUL, summ), nil
+}
+
+// trianglePoints return double integral by quadrature scheme on
+// triangle. Bounds of inner integral are linear and width of domain is
+// zero on one of bounds of outer integral.
+//
+//	integral2(f, x, a, b, y, c, d, triangle1) = area*f(xc, yc)
+func (s *sm) trianglePoints(call goast.Expr, sc scheme, function goast.Expr, bounds []goast.Expr) (goast.Expr, error) {
+	var (
+		x, a, b = bounds[0], bounds[1], bounds[2]
+		y, c, d = bounds[3], bounds[4], bounds[5]
+		nx, ny  = astToStr(x), astToStr(y)
+	)
+	notTriangle := s.errorGen(&UnsupportedError{
+		Location: Location{Expr: astToStr(call)},
+		Msg:      "domain of integral is not triangle",
+	})
+	for _, bound := range []goast.Expr{c, d} {
+		if _, _, ok := s.linear(bound, nx); !ok && !s.independent(bound, nx) {
+			return nil, notTriangle
+		}
+	}
+	at := func(e, value goast.Expr) goast.Expr {
+		return substitute(e, map[string]goast.Expr{nx: value})
+	}
+
+	// width of domain on bounds
+	var widths [2]goast.Expr
+	var zero [2]bool
+	for i, bound := range []goast.Expr{a, b} {
+		w, err := s.simplify(binaryOf(at(d, bound), token.SUB, at(c, bound)))
+		if err != nil {
+			return nil, err
+		}
+		widths[i] = w
+		if ok, v := isNumber(w); ok && v == 0 {
+			zero[i] = true
+		}
+	}
+
+	// vertices of triangle and signed area
+	var vertices [3][2]goast.Expr
+	var area goast.Expr
+	length := s.addTerm(s.addTerm(nil, big.NewRat(1, 2), b), big.NewRat(-1, 2), a)
+	switch {
+	case zero[1] && !zero[0]:
+		vertices = [3][2]goast.Expr{{a, at(c, a)}, {a, at(d, a)}, {b, at(c, b)}}
+		area = binaryOf(length, token.MUL, widths[0])
+	case zero[0] && !zero[1]:
+		vertices = [3][2]goast.Expr{{b, at(c, b)}, {b, at(d, b)}, {a, at(c, a)}}
+		area = binaryOf(length, token.MUL, widths[1])
+	default:
+		return nil, notTriangle
+	}
+
+	values := make([]goast.Expr, len(sc.points))
+	for i := range sc.points {
+		var point [2]goast.Expr
+		for k := range point {
+			p, err := s.weightedSum(sc.points[i], []goast.Expr{
+				vertices[0][k], vertices[1][k], vertices[2][k],
+			})
+			if err != nil {
+				return nil, err
+			}
+			point[k] = p
+		}
+		values[i] = substitute(function, map[string]goast.Expr{nx: point[0], ny: point[1]})
+	}
+	summ, err := s.weightedSum(sc.weights, values)
+	if err != nil {
+		return nil, err
+	}
+	return binaryOf(area, token.MUL, summ), nil
+}
+
+// weightedSum return sum of values with weights. Numbers are
+// summarized exactly.
+func (s *sm) weightedSum(weights []string, values []goast.Expr) (goast.Expr, error) {
+	var summ goast.Expr
+	number := new(big.Rat)
+	for i := range values {
+		w, err := s.rat(weights[i])
+		if err != nil {
+			return nil, err
+		}
+		if ok, v := isRational(values[i]); ok {
+			number.Add(number, v.Mul(v, w))
+			continue
+		}
+		if w.Sign() != 0 {
+			summ = s.addTerm(summ, w, values[i])
+		}
+	}
+	if summ == nil || number.Sign() != 0 {
+		summ = s.addTerm(summ, number, nil)
+	}
+	return summ, nil
+}
+
+// rat return rational value of number or fraction, like `1/3`
+func (s *sm) rat(value string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: value},
+			Msg:      "not valid number",
+		})
+	}
+	return r, nil
+}
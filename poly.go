@@ -0,0 +1,729 @@
+package sm
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math/big"
+	"sort"
+	"strings"
+
+	goast "go/ast"
+)
+
+// polynomial is sum of monomials. Atoms of monomials are names and other
+// factors without sums, like `sin(x)` or `pow(x,a)`. Atoms are stored
+// by string representation.
+type polynomial struct {
+	terms []monomial
+	atoms map[string]goast.Expr
+}
+
+// monomial is product of rational coefficient and powers of atoms.
+// Atom with negative power is in denominator.
+type monomial struct {
+	coeff  *big.Rat
+	powers map[string]int
+}
+
+// key return atoms with powers in sorted order
+func (m monomial) key() string {
+	var names []string
+	for name := range m.powers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s^%d;", name, m.powers[name])
+	}
+	return b.String()
+}
+
+// degree return sum of powers of atoms
+func (m monomial) degree() (d int) {
+	for _, n := range m.powers {
+		d += n
+	}
+	return
+}
+
+// inverse return monomial `1/m`
+func (m monomial) inverse() monomial {
+	r := monomial{coeff: new(big.Rat).Inv(m.coeff), powers: map[string]int{}}
+	for name, n := range m.powers {
+		r.powers[name] = -n
+	}
+	return r
+}
+
+// add monomial to polynomial with summation of similar monomials
+func (p *polynomial) add(m monomial) {
+	for name, n := range m.powers {
+		if n == 0 {
+			delete(m.powers, name)
+		}
+	}
+	key := m.key()
+	for i := range p.terms {
+		if p.terms[i].key() != key {
+			continue
+		}
+		p.terms[i].coeff.Add(p.terms[i].coeff, m.coeff)
+		if p.terms[i].coeff.Sign() == 0 {
+			p.terms = append(p.terms[:i], p.terms[i+1:]...)
+		}
+		return
+	}
+	if m.coeff.Sign() != 0 {
+		p.terms = append(p.terms, m)
+	}
+}
+
+// poly return polynomial of expanded expression
+//
+//	a*x*x + 2*x*a - x/y
+func (s *sm) poly(e goast.Expr) (p polynomial) {
+	p.atoms = map[string]goast.Expr{}
+	for _, part := range parseSummArray(e) {
+		q := s.parseQuoArray(part.value)
+		m := monomial{coeff: big.NewRat(1, 1), powers: map[string]int{}}
+		if part.isNegative {
+			m.coeff.Neg(m.coeff)
+		}
+		for i, fs := range [][]goast.Expr{q.up, q.do} {
+			for _, f := range fs {
+				f = unparen(f)
+				if ok, v := isRational(f); ok && (i == 0 || v.Sign() != 0) {
+					if i == 0 {
+						m.coeff.Mul(m.coeff, v)
+					} else {
+						m.coeff.Quo(m.coeff, v)
+					}
+					continue
+				}
+				// from : pow(x,3)
+				// to   : x with power 3
+				base, n := f, 1
+				if call, ok := f.(*goast.CallExpr); ok && astToStr(call.Fun) == pow && len(call.Args) == 2 {
+					if ok, v := isRational(call.Args[1]); ok && v.IsInt() && v.Num().IsInt64() {
+						base, n = unparen(call.Args[0]), int(v.Num().Int64())
+					}
+				}
+				if i == 1 {
+					n = -n
+				}
+				key := astToStr(base)
+				p.atoms[key] = base
+				m.powers[key] += n
+			}
+		}
+		p.add(m)
+	}
+	return
+}
+
+// names return names of atoms of polynomial in alphabetical order.
+// Variables are first, if `variables` is true, otherwise last.
+func (s *sm) names(p polynomial, variables bool) (names []string) {
+	for name := range p.atoms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		vi, vj := s.isVariable(p.atoms[names[i]]), s.isVariable(p.atoms[names[j]])
+		if vi != vj {
+			return vi == variables
+		}
+		return names[i] < names[j]
+	})
+	return
+}
+
+// sortTerms sort monomials by degree and then by powers of atoms, so
+// that result is `x*x + a*x + b*x + a*b`.
+func (s *sm) sortTerms(p polynomial) {
+	names := s.names(p, true)
+	sort.SliceStable(p.terms, func(i, j int) bool {
+		a, b := p.terms[i], p.terms[j]
+		if a.degree() != b.degree() {
+			return a.degree() > b.degree()
+		}
+		for _, name := range names {
+			if a.powers[name] != b.powers[name] {
+				return a.powers[name] > b.powers[name]
+			}
+		}
+		return false
+	})
+}
+
+// monomialToAst return product of coefficient and powers of atoms. Atoms
+// with negative powers are in denominator. Atom `last` is placed at the end
+// of product.
+func (s *sm) monomialToAst(p polynomial, m monomial, last string) goast.Expr {
+	return s.product(s.monomialQuo(p, m, last))
+}
+
+// monomialQuo return factors of monomial. Coefficient 1 is omitted.
+func (s *sm) monomialQuo(p polynomial, m monomial, last string) (q quoArray) {
+	if m.coeff.Cmp(big.NewRat(1, 1)) != 0 || len(m.powers) == 0 {
+		q.up = append(q.up, s.createRat(m.coeff))
+	}
+	names := s.names(p, false)
+	for i := range names {
+		if names[i] == last {
+			names = append(append(names[:i:i], names[i+1:]...), last)
+			break
+		}
+	}
+	for _, name := range names {
+		for n := m.powers[name]; 0 < n; n-- {
+			q.up = append(q.up, p.atoms[name])
+		}
+		for n := m.powers[name]; n < 0; n++ {
+			q.do = append(q.do, p.atoms[name])
+		}
+	}
+	return
+}
+
+// product return left associative product of factors, like
+// `a*pow(x,2)/y`. Repeated factors are folded into power.
+func (s *sm) product(q quoArray) goast.Expr {
+	q = s.fold(q)
+	mul := func(fs []goast.Expr) (r goast.Expr) {
+		for _, f := range fs {
+			if r == nil {
+				r = f
+				continue
+			}
+			r = &goast.BinaryExpr{X: r, Op: token.MUL, Y: f}
+		}
+		return
+	}
+	up := mul(q.up)
+	if up == nil {
+		up = s.createFloat(1)
+	}
+	if len(q.do) == 0 {
+		return up
+	}
+	return &goast.BinaryExpr{X: up, Op: token.QUO, Y: mul(q.do)}
+}
+
+// polyToAst return sum of monomials in order of terms
+func (s *sm) polyToAst(p polynomial) goast.Expr {
+	if len(p.terms) == 0 {
+		return s.createFloat(0)
+	}
+	var summ summSlice
+	for _, m := range p.terms {
+		abs := monomial{coeff: new(big.Rat).Abs(m.coeff), powers: m.powers}
+		summ = append(summ, sliceSumm{
+			isNegative: m.coeff.Sign() < 0,
+			value:      s.monomialToAst(p, abs, ""),
+		})
+	}
+	return summ.toAst()
+}
+
+// content return positive greatest common divisor of numerators of
+// coefficients divided by least common multiple of denominators, so that
+// polynomial divided by content has integer coefficients without common
+// divisor.
+func (p polynomial) content() *big.Rat {
+	num, den := new(big.Int), big.NewInt(1)
+	for _, m := range p.terms {
+		num.GCD(nil, nil, num, new(big.Int).Abs(m.coeff.Num()))
+		g := new(big.Int).GCD(nil, nil, den, m.coeff.Denom())
+		den.Mul(den, new(big.Int).Quo(m.coeff.Denom(), g))
+	}
+	if num.Sign() == 0 {
+		return big.NewRat(1, 1)
+	}
+	return new(big.Rat).SetFrac(num, den)
+}
+
+// divide return polynomial divided by monomial
+func (p polynomial) divide(by monomial) (r polynomial) {
+	r.atoms = p.atoms
+	for _, m := range p.terms {
+		d := monomial{coeff: new(big.Rat).Quo(m.coeff, by.coeff), powers: map[string]int{}}
+		for name, n := range m.powers {
+			d.powers[name] = n
+		}
+		for name, n := range by.powers {
+			d.powers[name] -= n
+		}
+		r.add(d)
+	}
+	return
+}
+
+// outputForms replace output form `expand`, `collect` or `factor` of
+// whole simplified expression by formatted expression. Form of whole
+// expression is applied after simplification, because simplification
+// expands products. Forms inside other operations are replaced by rule
+// form.
+func (s *sm) outputForms(out string) (_ string, err error) {
+	e, err := parser.ParseExpr(out)
+	if err != nil {
+		return "", s.errorGen(err)
+	}
+	call, ok, err := s.formCall(e)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return out, nil
+	}
+	s.base = out
+	r, err := s.applyForm(call)
+	if err != nil {
+		return "", err
+	}
+	return astToStr(r), nil
+}
+
+// form is rule of output forms `expand`, `collect` and `factor` inside
+// other operations. Form is replaced by argument in canonical form.
+//
+//	d(expand((x+1)*(x+1)),x)
+//	d(pow(x,2)+2*x+1,x)
+func (s *sm) form(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok, err := s.formCall(e)
+	if err != nil {
+		return false, nil, err
+	}
+	if !ok || e == s.top {
+		// form of whole expression, see outputForms
+		return false, nil, nil
+	}
+	r, err = s.applyForm(call)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, r, nil
+}
+
+// formCall return call of output form with valid amount of arguments
+func (s *sm) formCall(e goast.Expr) (call *goast.CallExpr, ok bool, _ error) {
+	call, ok = e.(*goast.CallExpr)
+	if !ok {
+		return nil, false, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return nil, false, nil
+	}
+	expect := map[string]int{expandName: 1, collectName: 2, factorName: 1}
+	amount, ok := expect[id.Name]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(call.Args) != amount {
+		return nil, false, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     id.Name,
+			Args:     len(call.Args),
+			Expect:   amount,
+		})
+	}
+	return call, true, nil
+}
+
+// applyForm return argument of output form in form
+func (s *sm) applyForm(call *goast.CallExpr) (goast.Expr, error) {
+	location := Location{Expr: astToStr(call)}
+	name := astToStr(call.Fun)
+	form := func(arg goast.Expr) (goast.Expr, error) {
+		switch name {
+		case expandName:
+			p := s.poly(arg)
+			s.sortTerms(p)
+			return s.polyToAst(p), nil
+		case collectName:
+			return s.collect(location, arg, call.Args[1])
+		}
+		return s.factor(arg), nil
+	}
+	arg := call.Args[0]
+	mt, ok, err := isMatrix(arg)
+	if err != nil {
+		return nil, s.errorGen(err)
+	}
+	if !ok {
+		return form(arg)
+	}
+	for i := range mt.Args {
+		if mt.Args[i], err = form(mt.Args[i]); err != nil {
+			return nil, err
+		}
+	}
+	return s.matrixToAst(mt), nil
+}
+
+// atom return name of atom or error for numbers and sums
+func (s *sm) atom(location Location, e goast.Expr) (string, error) {
+	e = unparen(e)
+	if ok, _ := isNumber(e); ok || 1 < len(parseSummArray(e)) {
+		return "", s.errorGen(&UnsupportedError{
+			Location: location,
+			Msg:      fmt.Sprintf("not valid atom of polynomial: %s", astToStr(e)),
+		})
+	}
+	return astToStr(e), nil
+}
+
+// collect return sum of terms grouped by powers of atom in descending
+// order of power
+//
+//	collect(a*x*x + x*b + c*x + d, x) = a*x*x + (b+c)*x + d
+func (s *sm) collect(location Location, e, x goast.Expr) (goast.Expr, error) {
+	name, err := s.atom(location, x)
+	if err != nil {
+		return nil, err
+	}
+	p := s.poly(e)
+	p.atoms[name] = unparen(x)
+	groups := map[int]*polynomial{}
+	var powers []int
+	for _, m := range p.terms {
+		n := m.powers[name]
+		if _, ok := groups[n]; !ok {
+			groups[n] = &polynomial{atoms: p.atoms}
+			powers = append(powers, n)
+		}
+		c := monomial{coeff: new(big.Rat).Set(m.coeff), powers: map[string]int{}}
+		for atom, k := range m.powers {
+			if atom != name {
+				c.powers[atom] = k
+			}
+		}
+		groups[n].add(c)
+	}
+	if len(powers) == 0 {
+		return s.createFloat(0), nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(powers)))
+
+	var summ summSlice
+	for _, n := range powers {
+		c := *groups[n]
+		s.sortTerms(c)
+		if n == 0 || len(c.terms) == 1 {
+			for _, m := range c.terms {
+				abs := monomial{coeff: new(big.Rat).Abs(m.coeff), powers: map[string]int{name: n}}
+				for atom, k := range m.powers {
+					abs.powers[atom] = k
+				}
+				summ = append(summ, sliceSumm{
+					isNegative: m.coeff.Sign() < 0,
+					value:      s.monomialToAst(p, abs, name),
+				})
+			}
+			continue
+		}
+		q := s.monomialQuo(p, monomial{coeff: big.NewRat(1, 1), powers: map[string]int{name: n}}, name)
+		q.up = append([]goast.Expr{s.polyToAst(c)}, q.up...)
+		summ = append(summ, sliceSumm{value: s.product(q)})
+	}
+	return summ.toAst(), nil
+}
+
+// factor return product of numeric content, common monomial, linear
+// factors by atoms and rest polynomial
+//
+//	factor(2*x*x*x - 2*x) = 2*x*(x - 1)*(x + 1)
+//	factor(a*a - b*b)     = (a - b)*(a + b)
+func (s *sm) factor(e goast.Expr) goast.Expr {
+	p := s.poly(e)
+	if len(p.terms) == 0 {
+		return s.createFloat(0)
+	}
+	s.sortTerms(p)
+
+	// common monomial
+	common := monomial{coeff: p.content(), powers: map[string]int{}}
+	if p.terms[0].coeff.Sign() < 0 {
+		common.coeff.Neg(common.coeff)
+	}
+	for name := range p.atoms {
+		low, high := p.terms[0].powers[name], p.terms[0].powers[name]
+		for _, m := range p.terms {
+			if n := m.powers[name]; n < low {
+				low = n
+			} else if high < n {
+				high = n
+			}
+		}
+		switch {
+		case 0 < low:
+			common.powers[name] = low
+		case high < 0:
+			common.powers[name] = high
+		}
+	}
+	rest := p.divide(common)
+
+	// linear factors by all atoms, variables are first
+	var factors []goast.Expr
+	for _, name := range s.names(p, true) {
+		fs, r := rest.linearFactors(name)
+		for _, f := range fs {
+			// from : -3*b + 2*a
+			// to   : 2*a - 3*b
+			s.sortTerms(f)
+			sort.SliceStable(f.terms, func(i, j int) bool {
+				return f.terms[i].powers[name] > f.terms[j].powers[name]
+			})
+			factors = append(factors, s.polyToAst(f))
+		}
+		rest = r
+	}
+
+	// numeric content of rest polynomial is part of common monomial
+	s.sortTerms(rest)
+	c := rest.content()
+	if rest.terms[0].coeff.Sign() < 0 {
+		c.Neg(c)
+	}
+	rest = rest.divide(monomial{coeff: c, powers: map[string]int{}})
+	common.coeff.Mul(common.coeff, c)
+	if !rest.constant() {
+		factors = append(factors, s.polyToAst(rest))
+	}
+
+	// parens are added by printer, for example: -(2*x*(x+1))
+	negative := common.coeff.Sign() < 0
+	common.coeff.Abs(common.coeff)
+	var q quoArray
+	if len(factors) == 0 || common.coeff.Cmp(big.NewRat(1, 1)) != 0 || len(common.powers) != 0 {
+		q = s.monomialQuo(p, common, "")
+	}
+	q.up = append(q.up, factors...)
+	r := s.product(q)
+	if negative {
+		return &goast.UnaryExpr{Op: token.SUB, X: r}
+	}
+	return r
+}
+
+// linearFactors return primitive factors of polynomial, which are
+// linear by atom, and rest polynomial. Factor `u*x - v` is found, if
+// `u` is divisor of leading coefficient and `v` is divisor of free
+// coefficient by atom `x`.
+//
+//	x*x - a*a = (x - a)*(x + a)
+func (p polynomial) linearFactors(name string) (factors []polynomial, rest polynomial) {
+	rest = p
+	atom := monomial{coeff: big.NewRat(1, 1), powers: map[string]int{name: 1}}
+	for 0 < rest.degreeOf(name) && rest.integral() {
+		// coefficients of primitive polynomial are integer
+		primitive := rest.divide(monomial{coeff: rest.content(), powers: map[string]int{}})
+		free := primitive.coefficientOf(name, 0)
+		if len(free.terms) == 0 {
+			// factor `x`
+			var f polynomial
+			f.atoms = p.atoms
+			f.add(atom)
+			factors = append(factors, f)
+			rest = rest.divide(atom)
+			continue
+		}
+		lead := primitive.coefficientOf(name, rest.degreeOf(name))
+		// values of other atoms for fast check of roots
+		values := map[string]*big.Rat{}
+		for i, other := range rest.vars() {
+			if other != name {
+				values[other] = big.NewRat(int64(i+2), 1)
+			}
+		}
+		found := false
+	search:
+		for _, v := range free.divisors() {
+			for _, u := range lead.divisors() {
+				for _, sign := range []int64{1, -1} {
+					f := u.mul(rest.one().times(atom)).sub(v.times(monomial{
+						coeff:  big.NewRat(sign, 1),
+						powers: map[string]int{},
+					}))
+					f = f.divide(monomial{coeff: f.content(), powers: map[string]int{}})
+					// root `x = v/u` in point of other atoms
+					if uv, ok := u.evaluate(values); ok && uv.Sign() != 0 {
+						vv, _ := v.evaluate(values)
+						values[name] = new(big.Rat).Quo(vv, uv)
+						values[name].Mul(values[name], big.NewRat(sign, 1))
+						value, ok := rest.evaluate(values)
+						delete(values, name)
+						if ok && value.Sign() != 0 {
+							continue
+						}
+					}
+					if q, ok := rest.quo(f); ok {
+						factors = append(factors, f)
+						rest = q
+						rest.atoms = p.atoms
+						found = true
+						break search
+					}
+				}
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return
+}
+
+// divisors return divisors of polynomial without negative powers as
+// products of positive divisor of numerator of content, divisor of
+// common monomial and primitive rest polynomial, if rest is not
+// number.
+func (p polynomial) divisors() (ds []polynomial) {
+	c := p.content()
+	low := map[string]int{}
+	for i, m := range p.terms {
+		for _, name := range p.vars() {
+			if n := m.powers[name]; i == 0 || n < low[name] {
+				low[name] = n
+			}
+		}
+	}
+	rest := p.divide(monomial{coeff: c, powers: low})
+	ints := divisors(c.Num())
+	if len(ints) == 0 {
+		// large content
+		ints = []*big.Int{big.NewInt(1), c.Num()}
+	}
+	monomials := []monomial{{coeff: big.NewRat(1, 1), powers: map[string]int{}}}
+	for _, name := range p.vars() {
+		var next []monomial
+		for k := 0; k <= low[name]; k++ {
+			for _, m := range monomials {
+				d := monomial{coeff: m.coeff, powers: map[string]int{}}
+				for atom, n := range m.powers {
+					d.powers[atom] = n
+				}
+				d.powers[name] = k
+				next = append(next, d)
+			}
+		}
+		monomials = next
+	}
+	parts := []polynomial{p.one()}
+	if !rest.constant() {
+		parts = append(parts, rest)
+	}
+	for _, part := range parts {
+		for _, n := range ints {
+			for _, m := range monomials {
+				ds = append(ds, part.times(monomial{coeff: new(big.Rat).SetInt(n), powers: m.powers}))
+			}
+		}
+	}
+	return
+}
+
+// divisors return positive divisors of value. Result is empty for
+// large values.
+func divisors(v *big.Int) (ds []*big.Int) {
+	v = new(big.Int).Abs(v)
+	const limit = 1000000
+	if !v.IsInt64() || limit < v.Int64() {
+		return nil
+	}
+	n := v.Int64()
+	var large []*big.Int
+	for d := int64(1); d*d <= n; d++ {
+		if n%d != 0 {
+			continue
+		}
+		ds = append(ds, big.NewInt(d))
+		if d*d != n {
+			large = append(large, big.NewInt(n/d))
+		}
+	}
+	for i := len(large) - 1; 0 <= i; i-- {
+		ds = append(ds, large[i])
+	}
+	return
+}
+
+// evaluate return value of polynomial by values of atoms. Result is not
+// ok for atom without value or for division by zero.
+func (p polynomial) evaluate(values map[string]*big.Rat) (_ *big.Rat, ok bool) {
+	summ := new(big.Rat)
+	for _, m := range p.terms {
+		v := new(big.Rat).Set(m.coeff)
+		for name, n := range m.powers {
+			x, ok := values[name]
+			if !ok || (n < 0 && x.Sign() == 0) {
+				return nil, false
+			}
+			for k := 0; k < n; k++ {
+				v.Mul(v, x)
+			}
+			for k := 0; k < -n; k++ {
+				v.Quo(v, x)
+			}
+		}
+		summ.Add(summ, v)
+	}
+	return summ, true
+}
+
+// coeff is coefficient of polynomial by power of atom
+//
+//	coeff(a*x*x + b*x + c, x, 2) = a
+func (s *sm) coeff(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != coeffName {
+		return false, nil, nil
+	}
+	location := Location{Expr: astToStr(call)}
+	if len(call.Args) != 3 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: location,
+			Name:     coeffName,
+			Args:     len(call.Args),
+			Expect:   3,
+		})
+	}
+	name, err := s.atom(location, call.Args[1])
+	if err != nil {
+		return false, nil, err
+	}
+	ok, v := isRational(call.Args[2])
+	if !ok || !v.IsInt() || !v.Num().IsInt64() {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: location,
+			Msg:      fmt.Sprintf("power is not integer: %s", astToStr(call.Args[2])),
+		})
+	}
+	n := int(v.Num().Int64())
+	p := s.poly(call.Args[0])
+	c := polynomial{atoms: p.atoms}
+	for _, m := range p.terms {
+		if m.powers[name] != n {
+			continue
+		}
+		d := monomial{coeff: new(big.Rat).Set(m.coeff), powers: map[string]int{}}
+		for atom, k := range m.powers {
+			if atom != name {
+				d.powers[atom] = k
+			}
+		}
+		c.add(d)
+	}
+	s.sortTerms(c)
+	return true, s.polyToAst(c), nil
+}
@@ -0,0 +1,334 @@
+package sm
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+
+	goast "go/ast"
+)
+
+// quadratureTolerance is default tolerance of numeric integration
+const quadratureTolerance = 1e-9
+
+// NIntegral return numeric value of definite integral of expression by
+// variable from `a` to `b` and estimate of absolute error. Values of
+// other names are in bindings. Integration is adaptive Gauss-Kronrod
+// quadrature with 15 points and absolute error of result is less then
+// `tol`. Parts of expression are simplified before integration, see
+// Eval.
+// Example:
+//
+//	expr     : "exp(-x*x)"
+//	variable : "x"
+//	a, b     : 0, 1
+//	tol      : 1e-9
+//	value    : 0.746824132812427
+func NIntegral(expr string, bindings map[string]float64, variable string, a, b, tol float64) (value, estimate float64, err error) {
+	s, e, err := evalPrepare(expr, bindings)
+	if err != nil {
+		return
+	}
+	if _, ok, _ := isMatrix(e); ok {
+		return 0, 0, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(e)},
+			Msg:      "integrand is matrix",
+		})
+	}
+	values := map[string]float64{}
+	for name, v := range bindings {
+		values[name] = v
+	}
+	return s.quadrature(func(t float64) (float64, error) {
+		values[variable] = t
+		return s.evaluate(e, values)
+	}, a, b, tol, astToStr(e))
+}
+
+// nintegral is numeric integration
+//
+//	nintegral(f, x, a, b)
+//	nintegral(f, x, a, b, tol)
+func (s *sm) nintegral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != nintegralName {
+		return false, nil, nil
+	}
+	if len(call.Args) != 4 && len(call.Args) != 5 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     nintegralName,
+			Args:     len(call.Args),
+			Expect:   5,
+		})
+	}
+	if s.opts.Exact {
+		// numeric value is not exact, it is calculated after
+		// simplification, see approximate
+		return false, nil, nil
+	}
+	value, err := s.nintegralValue(call)
+	if err != nil {
+		return false, nil, err
+	}
+	return true, s.createFloat(value), nil
+}
+
+// nintegralValue return numeric value of call `nintegral` with valid
+// amount of arguments
+func (s *sm) nintegralValue(call *goast.CallExpr) (value float64, err error) {
+	tol := goast.Expr(nil)
+	if len(call.Args) == 5 {
+		tol = call.Args[4]
+	}
+	value, _, err = s.numericIntegral(call, call.Args[0], call.Args[1], call.Args[2], call.Args[3], tol)
+	return
+}
+
+// approximate replace numeric integrals of simplified expression by
+// decimals in Exact mode. Numeric value is not exact, so it is not
+// converted into fraction.
+// Example:
+//
+//	out : 2*nintegral(exp(-x*x),x,0,1)
+//	r   : 2*0.747
+func (s *sm) approximate(out string) (r string, err error) {
+	if !s.opts.Exact || !strings.Contains(out, nintegralName) {
+		return out, nil
+	}
+	s.base = out
+	e, err := parser.ParseExpr(out)
+	if err != nil {
+		return "", s.errorGen(err)
+	}
+	var conv func(e goast.Expr) (goast.Expr, error)
+	conv = func(e goast.Expr) (goast.Expr, error) {
+		var err error
+		switch v := e.(type) {
+		case *goast.BinaryExpr:
+			if v.X, err = conv(v.X); err != nil {
+				return nil, err
+			}
+			v.Y, err = conv(v.Y)
+		case *goast.UnaryExpr:
+			v.X, err = conv(v.X)
+		case *goast.ParenExpr:
+			v.X, err = conv(v.X)
+		case *goast.CallExpr:
+			for i := range v.Args {
+				if v.Args[i], err = conv(v.Args[i]); err != nil {
+					return nil, err
+				}
+			}
+			if id, ok := v.Fun.(*goast.Ident); !ok || id.Name != nintegralName {
+				break
+			}
+			if len(v.Args) != 4 && len(v.Args) != 5 {
+				return nil, s.errorGen(&ArityError{
+					Location: Location{Expr: astToStr(v)},
+					Name:     nintegralName,
+					Args:     len(v.Args),
+					Expect:   5,
+				})
+			}
+			value, err := s.nintegralValue(v)
+			if err != nil {
+				return nil, err
+			}
+			return &goast.BasicLit{
+				Kind:  token.FLOAT,
+				Value: fmt.Sprintf("%.*f", s.opts.FloatFormat, value),
+			}, nil
+		}
+		return e, err
+	}
+	if e, err = conv(e); err != nil {
+		return "", err
+	}
+	return astToStr(e), nil
+}
+
+// numeric return true, if expression is evaluated by value of variable
+// only. Variable is empty for expression without names.
+func numeric(e goast.Expr, variable string) bool {
+	switch v := e.(type) {
+	case *goast.BasicLit:
+		return true
+	case *goast.Ident:
+		return variable != "" && v.Name == variable
+	case *goast.ParenExpr:
+		return numeric(v.X, variable)
+	case *goast.UnaryExpr:
+		return numeric(v.X, variable)
+	case *goast.BinaryExpr:
+		return numeric(v.X, variable) && numeric(v.Y, variable)
+	case *goast.CallExpr:
+		id, ok := v.Fun.(*goast.Ident)
+		if !ok {
+			return false
+		}
+		if f, ok := evalFunctions[id.Name]; !ok || f.args != len(v.Args) {
+			return false
+		}
+		for _, arg := range v.Args {
+			if !numeric(arg, variable) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// numericIntegral return numeric value of definite integral with
+// numeric bounds and integrand without names, except variable.
+// Tolerance is nil for default value. Call is location for errors.
+func (s *sm) numericIntegral(call, function, variable, begin, finish, tol goast.Expr) (value, estimate float64, err error) {
+	id, ok := unparen(variable).(*goast.Ident)
+	if !ok {
+		return 0, 0, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(variable)},
+			Msg:      "not valid name of variable",
+		})
+	}
+	a, err := s.evaluate(begin, nil)
+	if err != nil {
+		return
+	}
+	b, err := s.evaluate(finish, nil)
+	if err != nil {
+		return
+	}
+	t := quadratureTolerance
+	if tol != nil {
+		if t, err = s.evaluate(tol, nil); err != nil {
+			return
+		}
+		if t <= 0 {
+			return 0, 0, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(tol)},
+				Msg:      "tolerance is not positive",
+			})
+		}
+	}
+	value, estimate, err = s.quadrature(func(x float64) (float64, error) {
+		return s.evaluate(function, map[string]float64{external(id.Name): x})
+	}, a, b, t, astToStr(call))
+	if err != nil {
+		return
+	}
+	if s.opts.Trace != nil || s.opts.OnStep != nil {
+		s.emit(Step{
+			Rule:      "quadrature",
+			Before:    astToStr(call),
+			After:     strconv.FormatFloat(value, 'g', -1, 64),
+			Iteration: s.iter,
+			Estimate:  estimate,
+		})
+	}
+	return
+}
+
+// nodes and weights of Gauss-Kronrod quadrature with 15 points.
+// Gauss quadrature with 7 points use each second node of Kronrod.
+var (
+	kronrodNodes = [8]float64{
+		0.991455371120812639206854697526329,
+		0.949107912342758524526189684047851,
+		0.864864423359769072789712788640926,
+		0.741531185599394439863864773280788,
+		0.586087235467691130294144845693013,
+		0.405845151377397166906606412076961,
+		0.207784955007898467600689403773245,
+		0.000000000000000000000000000000000,
+	}
+	kronrodWeights = [8]float64{
+		0.022935322010529224963732008058970,
+		0.063092092629978553290700663189204,
+		0.104790010322250183839876322541518,
+		0.140653259715525918745189590510238,
+		0.169004726639267902826583426598550,
+		0.190350578064785409913256402421014,
+		0.204432940075298892414161999234649,
+		0.209482141084727828012999174891714,
+	}
+	gaussWeights = [4]float64{
+		0.129484966168869693270611432679082,
+		0.279705391489276667901467771423780,
+		0.381830050505118944950369775488975,
+		0.417959183673469387755102040816327,
+	}
+)
+
+// quadrature return numeric integral of function by adaptive
+// Gauss-Kronrod quadrature and estimate of absolute error
+// of integral from `a` to `b`. Expression is location for errors.
+func (s *sm) quadrature(f func(float64) (float64, error), a, b, tol float64, expr string) (value, estimate float64, err error) {
+	const (
+		depth     = 30
+		intervals = 1000
+	)
+	amount := 0
+	var adaptive func(a, b, tol float64, depth int) (value, estimate float64, err error)
+	adaptive = func(a, b, tol float64, depth int) (value, estimate float64, err error) {
+		center, half := (a+b)/2, (b-a)/2
+		var kronrod, gauss float64
+		for i := range kronrodNodes {
+			var fs [2]float64
+			for j, x := range [2]float64{center - half*kronrodNodes[i], center + half*kronrodNodes[i]} {
+				if fs[j], err = f(x); err != nil {
+					return
+				}
+			}
+			sum := fs[0] + fs[1]
+			if i == len(kronrodNodes)-1 {
+				sum = fs[0]
+			}
+			kronrod += kronrodWeights[i] * sum
+			if i%2 == 1 {
+				gauss += gaussWeights[i/2] * sum
+			}
+		}
+		value, estimate = kronrod*half, math.Abs((kronrod-gauss)*half)
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, 0, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: expr},
+				Msg:      fmt.Sprintf("numeric integral is not finite on [%g, %g]", a, b),
+			})
+		}
+		amount++
+		if estimate <= tol || depth == 0 || intervals < amount {
+			return
+		}
+		left, el, err := adaptive(a, center, tol/2, depth-1)
+		if err != nil {
+			return 0, 0, err
+		}
+		right, er, err := adaptive(center, b, tol/2, depth-1)
+		if err != nil {
+			return 0, 0, err
+		}
+		return left + right, el + er, nil
+	}
+	value, estimate, err = adaptive(a, b, tol, depth)
+	if err != nil {
+		return
+	}
+	if tol < estimate {
+		return value, estimate, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: expr},
+			Msg:      fmt.Sprintf("numeric integral is not converged, error estimate %g", estimate),
+		})
+	}
+	return
+}
@@ -0,0 +1,46 @@
+package sm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNIntegral(t *testing.T) {
+	for i, tc := range []struct {
+		expr     string
+		bindings map[string]float64
+		a, b     float64
+		out      float64
+	}{
+		{"x*x", nil, 0, 3, 9},
+		{"sin(x)", nil, 0, math.Pi, 2},
+		{"exp(-x*x)", nil, 0, 1, math.Sqrt(math.Pi) / 2 * math.Erf(1)},
+		{"sqrt(x)", nil, 0, 1, 2.0 / 3.0},
+		{"1/(1+a*x*x)", map[string]float64{"a": 1}, -1, 1, math.Pi / 2},
+		{"d(pow(x,4),x); variable(x)", nil, 1, 0, -1},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			out, estimate, err := NIntegral(tc.expr, tc.bindings, "x", tc.a, tc.b, 1e-10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if 1e-10 < estimate {
+				t.Fatalf("not valid estimate: %v", estimate)
+			}
+			if math.Abs(out-tc.out) > 1e-9 {
+				t.Fatalf("not same: %v != %v", out, tc.out)
+			}
+		})
+	}
+	for i, expr := range []string{"1/x", "a*x", "matrix(x,1,1)"} {
+		t.Run(fmt.Sprintf("error%d:%s", i, expr), func(t *testing.T) {
+			_, _, err := NIntegral(expr, nil, "x", 0, 1, 1e-10)
+			var e *UnsupportedError
+			if !errors.As(err, &e) {
+				t.Fatalf("not valid error: %v", err)
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@ package sm
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"fmt"
 	"go/parser"
 	"go/printer"
 	"go/token"
 	"io"
-	"os"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,17 +21,41 @@ import (
 )
 
 const (
-	pow          = "pow"
-	differential = "d"
-	matrix       = "matrix"
-	transpose    = "transpose"
-	det          = "det"
-	integralName = "integral"
-	injectName   = "inject"
-	inverse      = "inverse"
-	sinName      = "sin"
-	cosName      = "cos"
-	tanName      = "tan"
+	pow           = "pow"
+	differential  = "d"
+	matrix        = "matrix"
+	transpose     = "transpose"
+	det           = "det"
+	integralName  = "integral"
+	injectName    = "inject"
+	subsName      = "subs"
+	apartName     = "apart"
+	nintegralName = "nintegral"
+	integral2     = "integral2"
+	integral3     = "integral3"
+	expandName    = "expand"
+	collectName   = "collect"
+	factorName    = "factor"
+	coeffName     = "coeff"
+	cancelName    = "cancel"
+	gcdName       = "gcd"
+	inverse       = "inverse"
+	sinName       = "sin"
+	cosName       = "cos"
+	tanName       = "tan"
+	expName       = "exp"
+	logName       = "log"
+	sqrtName      = "sqrt"
+	asinName      = "asin"
+	acosName      = "acos"
+	atanName      = "atan"
+	sinhName      = "sinh"
+	coshName      = "cosh"
+	tanhName      = "tanh"
+	absName       = "abs"
+	hessian       = "hessian"
+	gradient      = "gradient"
+	jacobian      = "jacobian"
 )
 
 func internalNames() []string {
@@ -41,13 +67,125 @@ func internalNames() []string {
 		det,
 		integralName,
 		injectName,
+		subsName,
+		apartName,
+		nintegralName,
+		integral2,
+		integral3,
+		expandName,
+		collectName,
+		factorName,
+		coeffName,
+		cancelName,
+		gcdName,
 		inverse,
 		sinName,
 		cosName,
 		tanName,
+		expName,
+		logName,
+		sqrtName,
+		asinName,
+		acosName,
+		atanName,
+		sinhName,
+		coshName,
+		tanhName,
+		absName,
+		hessian,
+		gradient,
+		jacobian,
 	}
 }
 
+// isElementary return true for elementary functions with one argument,
+// like `sin`, `exp`, `log`
+func isElementary(name string) bool {
+	switch name {
+	case sinName, cosName, tanName, expName, logName, sqrtName,
+		asinName, acosName, atanName, sinhName, coshName, tanhName,
+		absName:
+		return true
+	}
+	return false
+}
+
+// Options of simplification. Zero value of field is default value.
+type Options struct {
+	// MaxIteration is limit of iterations. Negative value is unlimited.
+	// Default value is 1000000.
+	MaxIteration int64
+
+	// FloatFormat is amount of digits after point for float values, for more
+	// precision calculation use value equal 12. Default value is 3.
+	FloatFormat int
+
+	// RepeatMax is limit of repeated intermediate results.
+	// Default value is 10.
+	RepeatMax int
+
+	// Exact is mode of exact rational arithmetic. In that mode all numbers
+	// are kept as fractions, for example `1/3` instead of `0.333`.
+	// For convert fractions into decimals use function Decimal.
+	// Numeric integrals are not exact and printed as decimals.
+	Exact bool
+
+	// Out is writer for intermediate results of simplification.
+	// Nil is allowed.
+	Out io.Writer
+
+	// Trace is writer for trace of simplification rules.
+	// Nil is allowed.
+	Trace io.Writer
+
+	// OnStep is called after each step of simplification by rule.
+	// Calls are serialized, but parts of expression are simplified
+	// in parallel, so order of steps from different parts is not
+	// defined. Nil is allowed.
+	OnStep func(Step)
+}
+
+func (o Options) defaults() Options {
+	if o.MaxIteration == 0 {
+		o.MaxIteration = 1000000
+	}
+	if o.FloatFormat == 0 {
+		o.FloatFormat = 3
+	}
+	if o.RepeatMax == 0 {
+		o.RepeatMax = 10
+	}
+	if o.Out == nil {
+		o.Out = io.Discard
+	}
+	o.Out = &syncWriter{w: o.Out}
+	if o.Trace != nil {
+		o.Trace = &syncWriter{w: o.Trace}
+	}
+	if o.OnStep != nil {
+		var mu sync.Mutex
+		onStep := o.OnStep
+		o.OnStep = func(st Step) {
+			mu.Lock()
+			defer mu.Unlock()
+			onStep(st)
+		}
+	}
+	return o
+}
+
+// syncWriter is writer for parallel simplification of parts
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
 type sm struct {
 	base string
 	expr string
@@ -56,6 +194,16 @@ type sm struct {
 	funs []function
 	iter int64
 	out  io.Writer
+	opts Options
+	ctx  context.Context
+
+	// top is whole expression of simplification iteration
+	top goast.Expr
+
+	// segments of input expression separated by `;` and index of
+	// segment with expression for simplification
+	segments []string
+	segment  int
 }
 
 func (s sm) copy() (c sm) {
@@ -65,6 +213,10 @@ func (s sm) copy() (c sm) {
 	c.vars = append([]string{}, s.vars...)
 	c.funs = append([]function{}, s.funs...)
 	c.out = s.out
+	c.opts = s.opts
+	c.ctx = s.ctx
+	c.segments = s.segments
+	c.segment = s.segment
 	return
 }
 
@@ -130,6 +282,11 @@ func (s sm) isFunction(name, arg string) bool {
 }
 
 func (s sm) errorGen(e error) error {
+	var loc *Location
+	if l, ok := e.(interface{ location() *Location }); ok {
+		loc = l.location()
+		loc.locate(s)
+	}
 	var et errors.Tree
 	et.Name = "Error of symbolic math"
 	_ = et.Add(fmt.Errorf("Expression: %s", s.base))
@@ -159,17 +316,23 @@ func (s sm) errorGen(e error) error {
 	}
 	_ = et.Add(fmt.Errorf("Iteration : %d", s.iter))
 	_ = et.Add(fmt.Errorf("Error     : %v", e))
-	return et
+	if loc != nil && 0 <= loc.Segment {
+		var ei errors.Tree
+		ei.Name = fmt.Sprintf("Position  : segment %d, column %d", loc.Segment, loc.Column)
+		// separator `|` is protection of spaces before caret
+		_ = ei.Add(fmt.Errorf("| %s", s.segments[loc.Segment]))
+		_ = ei.Add(fmt.Errorf("| %s", caret(s.segments[loc.Segment], loc.Column)))
+		_ = et.Add(ei)
+	}
+	return &Error{Err: e, tree: et}
 }
 
-var MaxIteration int64 = 1000000
-
 func (s sm) iterationLimit() error {
-	if MaxIteration < 0 {
+	if s.opts.MaxIteration < 0 {
 		s.iter = 0
 		return nil
 	}
-	if MaxIteration < s.iter {
+	if s.opts.MaxIteration < s.iter {
 		return s.errorGen(fmt.Errorf("iteration limit"))
 	}
 	return nil
@@ -190,16 +353,16 @@ type function struct {
 //	out:  "2.000*(a*pow(x,a - 1.000))",
 //
 //	expr: "b*(2+8*a)*a; constant(a); constant(b)",
-//	out:  "2.000*(a*b) + 8.000*(a*(a*b))",
+//	out:  "2.000*(a*b) + 8.000*(pow(a,2.000)*b)",
 //	
 //	expr: "(a + b) * (c - d - s); constant(a,b,c,d,s)",
 //	out:  "a*c-a*d-a*s+(b*c-b*d-b*s)",
 //	
 //	expr: "pow(a,5-3+1)",
-//	out:  "a * (a * a)",
+//	out:  "pow(a, 3.000)",
 //	
 //	expr: "pow(a+1,2)",
-//	out:  "1.000+2.000*a+a*a",
+//	out:  "1.000+2.000*a+pow(a,2.000)",
 //	
 //	expr: "b*d(a*x,x);constant(a);variable(x);",
 //	out:  "a * b",
@@ -231,6 +394,43 @@ type function struct {
 //	expr: "inverse(matrix(1,2,3,0,1,4,5,6,0,3,3))",
 //	out:  "matrix(-24.000,18.000,5.000,20.000,-15.000,-4.000,-5.000,4.000,1.000,3.000,3.000)",
 //
+// Operators `^` and `**` are power, for example `x^2` is `pow(x,2)`.
+// Operand after operand is multiplication, for example `2x(a+b)` and
+// `2x (a+b)` are `2*x*(a+b)`, if `x` is not function. Names are unicode
+// with subscripts `x_1`, `x₁`, `x_{ij}` and primes `u'`.
+//
+// Elementary functions: sin, cos, tan, exp, log, sqrt, asin, acos, atan,
+// sinh, cosh, tanh, abs.
+//
+// Derivatives: `d(f,x)`, `d(f,x,2)` for second derivative, `d(f,x,y)` for
+// mixed partial derivative, `gradient(f,x,y)`, `hessian(f,x,y)` and
+// `jacobian(matrix(f,g,2,1),x,y)`.
+//
+// Integrals: `integral(f,x)` for indefinite integral and
+// `integral(f,x,a,b)` for definite integral. Partial fractions of
+// rational function: `apart(f,x)`. Definite integral without closed
+// form is calculated numerically for numeric bounds and integrand.
+// Numeric integral with tolerance: `nintegral(f,x,a,b,tol)`, estimate of
+// error is in Step with rule `quadrature`.
+// Multiple integrals: `integral2(f,x,a,b,y,c,d)` and
+// `integral3(f,x,a,b,y,c,d,z,e,g)`, bounds of inner integral may depend
+// on outer variables. Last argument is optional quadrature scheme:
+// `gauss1`...`gauss5` on line, `triangle1`, `triangle3`, `triangle4`,
+// `triangle7` on triangle, for example `integral(f,x,a,b,gauss2)`.
+//
+// Polynomials: `coeff(f,x,n)` is coefficient of `x` in power `n`,
+// `gcd(p,q)` is greatest common divisor of polynomials and `cancel(f)` is
+// fraction without common divisor of numerator and denominator. Common
+// divisor of polynomials in fraction is cancelled, for example
+// `(x*x-1)/(x-1)` is `1+x`.
+// Output forms are applied after simplification: `expand(f)` is sum of
+// terms in order of degree, `collect(f,x)` is grouping of terms by powers
+// of `x` and `factor(f)` is product of common factor and linear factors
+// by rational roots. Forms inside other operations are replaced by
+// argument in that form, for example `d(factor(x*x-1),x)` is `2*x`.
+// Repeated factors are folded into power, for example `L*L*L` is
+// `pow(L,3)` and `a/(L*L)` is `a/pow(L,2)`.
+//
 //
 // Keywords:
 //
@@ -238,45 +438,143 @@ type function struct {
 //		variables(a); for variables
 //	 function(a,x,y,z,...); for function a(x,y,z)
 func Sexpr(o io.Writer, expr string) (out string, err error) {
-	if o == nil {
-		var buf bytes.Buffer
-		o = &buf
+	return SexprWithOptions(context.Background(), o, expr, Options{})
+}
+
+// SexprContext is simplification of expression with context. If context is
+// canceled or deadline is exceeded, then return error *CancelError.
+// For more details see Sexpr.
+func SexprContext(ctx context.Context, o io.Writer, expr string) (out string, err error) {
+	return SexprWithOptions(ctx, o, expr, Options{})
+}
+
+// SexprWithOptions is simplification of expression with options.
+// Intermediate results are written to `o`, if `o` is nil, then to `opts.Out`.
+// For more details see Sexpr.
+func SexprWithOptions(ctx context.Context, o io.Writer, expr string, opts Options) (out string, err error) {
+	if o != nil {
+		opts.Out = o
 	}
-	expr = strings.Replace(expr, "\n", "", -1)
 
 	var s sm
+	s.opts = opts.defaults()
+	s.out = s.opts.Out
+	s.ctx = ctx
+	if err = s.parse(expr); err != nil {
+		return "", err
+	}
+
+	// TODO : replace numbers(ints or floats) to constants and replace constant operations at last moment
+
+	out, err = s.run()
+	if err != nil {
+		return "", s.cancelError(err)
+	}
+	out, err = s.outputForms(out)
+	if err != nil {
+		return "", err
+	}
+	out, err = s.approximate(out)
+	if err != nil {
+		return "", err
+	}
+	return external(out), nil
+}
+
+// CancelError is error of canceled simplification, for example by
+// deadline of context. Expr is partially simplified expression.
+type CancelError struct {
+	Expr string
+	Err  error
+}
+
+func (e *CancelError) Error() string {
+	return fmt.Sprintf("%v. Partial result: %s", e.Err, e.Expr)
+}
+
+// Unwrap return error of context.
+func (e *CancelError) Unwrap() error {
+	return e.Err
+}
+
+// cancelError return *CancelError, if context is done
+func (s sm) cancelError(err error) error {
+	if ctxErr := s.ctx.Err(); ctxErr != nil {
+		return &CancelError{Expr: s.base, Err: ctxErr}
+	}
+	return err
+}
+
+// parse expression with keywords `constant`, `variable`, `function`
+func (s *sm) parse(expr string) error {
+	expr = strings.Replace(expr, "\n", "", -1)
 	s.base = expr
-	s.out = o
 
 	// split expression
 	lines := strings.Split(expr, ";")
+	s.segments = append([]string{}, lines...)
+	s.segment = -1
+	var base goast.Expr
+
+	// names of declared functions for calls with spaces before paren,
+	// for example `f (x)`
+	var declared []string
+	for i := range lines {
+		n, serr := parseInput(lines[i], functions())
+		if serr != nil || n.kind != callNode || n.value != "function" ||
+			len(n.args) == 0 || n.args[0].kind != nameNode {
+			continue
+		}
+		declared = append(declared, n.args[0].value)
+	}
+	calls := functions(declared...)
+
 	// parse to full expression to parts
 	for i := range lines {
 		if strings.TrimSpace(lines[i]) == "" {
 			continue
 		}
-		a, err := parser.ParseExpr(lines[i])
+		segment := i
+		n, serr := parseInput(lines[i], calls)
+		if serr != nil {
+			serr.Segment = segment
+			return s.errorGen(serr)
+		}
+		a, err := n.toAst()
 		if err != nil {
-			return "", s.errorGen(err)
+			if uerr, ok := err.(*UnsupportedError); ok {
+				uerr.Segment = segment
+			}
+			return s.errorGen(err)
+		}
+		syntax := func(node goast.Expr, msg string) error {
+			return s.errorGen(&SyntaxError{
+				Location: Location{
+					Expr:    astToStr(node),
+					Segment: segment,
+					Column:  int(node.Pos()),
+				},
+				Msg: msg,
+			})
 		}
 		if call, ok := a.(*goast.CallExpr); ok {
 			funIdent, ok := call.Fun.(*goast.Ident)
 			if !ok {
-				return "", s.errorGen(fmt.Errorf("not good function name: %s", lines[i]))
+				return syntax(call.Fun, "not good function name")
 			}
 			// function name
 			switch funIdent.Name {
 			case "function":
 				if len(call.Args) < 2 {
-					return "", s.errorGen(fmt.Errorf(
-						"function have minimal 2 arguments - name of function and depend variable"))
+					return syntax(call,
+						"function have minimal 2 arguments - name of function and depend variable")
 				}
 				var f function
 				// name of function
 				if id, ok := call.Args[0].(*goast.Ident); ok {
 					f.name = id.Name
 				} else {
-					return "", s.errorGen(fmt.Errorf("not valid name of function"))
+					return syntax(call.Args[0], "not valid name of function")
 				}
 				// depend variables
 				for i := 1; i < len(call.Args); i++ {
@@ -284,7 +582,7 @@ func Sexpr(o io.Writer, expr string) (out string, err error) {
 						f.variables = append(f.variables, id.Name)
 						s.vars = append(s.vars, id.Name)
 					} else {
-						return "", s.errorGen(fmt.Errorf("not valid name of variable"))
+						return syntax(call.Args[i], "not valid name of variable")
 					}
 				}
 				s.funs = append(s.funs, f)
@@ -294,23 +592,24 @@ func Sexpr(o io.Writer, expr string) (out string, err error) {
 					if id, ok := call.Args[i].(*goast.Ident); ok {
 						s.cons = append(s.cons, id.Name)
 					} else {
-						return "", s.errorGen(fmt.Errorf("not valid name of constant"))
+						return syntax(call.Args[i], "not valid name of constant")
 					}
 				}
 				continue
 			case "variable":
 				if len(call.Args) != 1 {
-					return "", s.errorGen(fmt.Errorf("variables have only one argument - name of variable"))
+					return syntax(call, "variables have only one argument - name of variable")
 				}
 				if id, ok := call.Args[0].(*goast.Ident); ok {
 					s.vars = append(s.vars, id.Name)
 				} else {
-					return "", s.errorGen(fmt.Errorf("not valid name of variable"))
+					return syntax(call.Args[0], "not valid name of variable")
 				}
 				continue
 			}
 		}
-		s.base = lines[i]
+		base = a
+		s.segment = segment
 	}
 
 	// avoid extra spaces in names
@@ -327,9 +626,83 @@ func Sexpr(o io.Writer, expr string) (out string, err error) {
 		}
 	}
 
-	// TODO : replace numbers(ints or floats) to constants and replace constant operations at last moment
+	// names are known only after all declarations
+	if base != nil {
+		a, err := s.implicit(base)
+		if err != nil {
+			return err
+		}
+		s.base = astToStr(a)
+	}
+	return nil
+}
+
+// isCallable return true for name of internal or declared function.
+// Declared constants and variables are not functions.
+func (s sm) isCallable(name string) bool {
+	for _, names := range [][]string{s.cons, s.vars} {
+		for i := range names {
+			if names[i] == name {
+				return false
+			}
+		}
+	}
+	for i := range s.funs {
+		if s.funs[i].name == name {
+			return true
+		}
+	}
+	for _, n := range internalNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
 
-	return s.run()
+// implicit return expression with calls of names, that are not
+// functions, replaced by multiplication
+//
+//	from : 2x(x+1) with variable x
+//	to   : 2*x*(x+1)
+func (s *sm) implicit(e goast.Expr) (_ goast.Expr, err error) {
+	switch v := e.(type) {
+	case *goast.ParenExpr:
+		v.X, err = s.implicit(v.X)
+	case *goast.UnaryExpr:
+		v.X, err = s.implicit(v.X)
+	case *goast.BinaryExpr:
+		if v.X, err = s.implicit(v.X); err != nil {
+			return nil, err
+		}
+		v.Y, err = s.implicit(v.Y)
+	case *goast.CallExpr:
+		for i := range v.Args {
+			if v.Args[i], err = s.implicit(v.Args[i]); err != nil {
+				return nil, err
+			}
+		}
+		id, ok := v.Fun.(*goast.Ident)
+		if !ok || s.isCallable(id.Name) {
+			break
+		}
+		if len(v.Args) != 1 {
+			return nil, s.errorGen(&SyntaxError{
+				Location: Location{
+					Expr:    astToStr(v),
+					Segment: s.segment,
+					Column:  int(v.Pos()),
+				},
+				Msg: fmt.Sprintf("`%s` is not function", external(id.Name)),
+			})
+		}
+		return &goast.BinaryExpr{
+			X:  id,
+			Op: token.MUL,
+			Y:  &goast.ParenExpr{X: v.Args[0]},
+		}, nil
+	}
+	return e, err
 }
 
 func (s *sm) run() (out string, err error) {
@@ -343,8 +716,12 @@ func (s *sm) run() (out string, err error) {
 	l := list.New()
 	var changed bool
 	var k goast.Expr
-	repeat, repeatMax := 0, 10
+	repeat := 0
 	for {
+		if err = s.ctx.Err(); err != nil {
+			return "", err
+		}
+
 		// remove parens
 		a, err = s.clean(a)
 		if err != nil {
@@ -357,6 +734,7 @@ func (s *sm) run() (out string, err error) {
 			return "", err
 		}
 
+		s.top = a
 		changed, k, err = s.walk(a)
 		if err != nil {
 			return "", err
@@ -370,7 +748,7 @@ func (s *sm) run() (out string, err error) {
 				listStr := e.Value.(string)
 				if listStr == str {
 					repeat++
-					if repeatMax < repeat {
+					if s.opts.RepeatMax < repeat {
 						return "", fmt.Errorf("Repeat result: %s", str)
 					}
 				}
@@ -419,15 +797,29 @@ func (s *sm) deeper(a goast.Expr, walker func(goast.Expr) (bool, goast.Expr, err
 		return walker(v.X)
 
 	case *goast.BasicLit:
+		if s.opts.Exact {
+			// from : 0.5
+			// to   : 1/2
+			if v.Kind == token.FLOAT {
+				return s.parseFloat(v.Value)
+			}
+			break
+		}
 		if v.Kind == token.INT {
-			return true, createFloat(v.Value), nil
+			return s.parseFloat(v.Value)
+		}
+		if v.Kind == token.FLOAT {
+			// not valid float, for example: 1e400
+			if _, err := strconv.ParseFloat(v.Value, 64); err != nil {
+				return s.parseFloat(v.Value)
+			}
 		}
 
 	case *goast.Ident: // ignore
 
 	case *goast.UnaryExpr:
 		if bas, ok := v.X.(*goast.BasicLit); ok {
-			return true, createFloat(fmt.Sprintf("%v%s", v.Op, bas.Value)), nil
+			return s.parseFloat(fmt.Sprintf("%v%s", v.Op, bas.Value))
 		}
 		c, e, err := walker(v.X)
 		if err != nil {
@@ -461,7 +853,10 @@ func (s *sm) deeper(a goast.Expr, walker func(goast.Expr) (bool, goast.Expr, err
 		}
 
 	default:
-		panic(fmt.Errorf("Add implementation for type %T", a))
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(a)},
+			Msg:      fmt.Sprintf("not supported type %T", a),
+		})
 	}
 	// all is not changed
 	return false, nil, nil
@@ -541,46 +936,62 @@ func (s *sm) mulbin(a goast.Expr) (result goast.Expr, err error) {
 }
 
 func (s *sm) walk(a goast.Expr) (c bool, result goast.Expr, _ error) {
+	// cancel of context
+	if err := s.ctx.Err(); err != nil {
+		return false, nil, err
+	}
 	// iteration limit
 	if err := s.iterationLimit(); err != nil {
 		return false, nil, err
 	}
 	s.iter++
 
-	for numRule, rule := range []func(goast.Expr) (bool, goast.Expr, error){
-		func(a goast.Expr) (bool, goast.Expr, error) {
+	for numRule, rule := range []struct {
+		name string
+		f    func(goast.Expr) (bool, goast.Expr, error)
+	}{
+		{"deeper", func(a goast.Expr) (bool, goast.Expr, error) {
 			return s.deeper(a, s.walk)
-		},
-		s.constants,
-		s.openParen,
-		s.insideParen,
-		s.sort,
-		s.functionPow,
-		s.oneMul,
-		s.divide,
-		s.binaryNumber,
-		s.zeroValueMul,
-		s.matrixTranspose,
-		s.matrixDet,
-		s.matrixInverse,
-		s.matrixMultiply,
-		s.matrixSum,
-		s.mulConstToMatrix,
-		s.differential,
-		s.integral,
-		s.inject,
+		}},
+		{"constants", s.constants},
+		{"openParen", s.openParen},
+		{"insideParen", s.insideParen},
+		{"sort", s.sort},
+		{"functionPow", s.functionPow},
+		{"foldPower", s.foldPower},
+		{"oneMul", s.oneMul},
+		{"divide", s.divide},
+		{"binaryNumber", s.binaryNumber},
+		{"zeroValueMul", s.zeroValueMul},
+		{"matrixShape", s.matrixShape},
+		{"matrixTranspose", s.matrixTranspose},
+		{"matrixDet", s.matrixDet},
+		{"matrixInverse", s.matrixInverse},
+		{"matrixMultiply", s.matrixMultiply},
+		{"matrixSum", s.matrixSum},
+		{"mulConstToMatrix", s.mulConstToMatrix},
+		{"differential", s.differential},
+		{"differentialMatrix", s.differentialMatrix},
+		{"elementary", s.elementary},
+		{"integral", s.integral},
+		{"integralMultiple", s.integralMultiple},
+		{"inject", s.inject},
+		{"subs", s.subs},
+		{"apart", s.apart},
+		{"nintegral", s.nintegral},
+		{"form", s.form},
+		{"coeff", s.coeff},
+		{"cancel", s.cancel},
+		{"gcd", s.gcd},
 	} {
-		changed, r, err := rule(a)
+		changed, r, err := rule.f(a)
 		if err != nil {
 			return false, a, err
 		}
 		if changed {
-			_ = numRule
-			_ = os.Stdout
-			// if numRule != 0 {
-			//    fmt.Fprintf(os.Stdout, "> rule = %d\n", numRule)
-			//    fmt.Fprintf(os.Stdout, "> from: %s --->to----> %s\n", astToStr(a), astToStr(r))
-			// }
+			if numRule != 0 {
+				s.step(rule.name, a, r)
+			}
 			a, err = parser.ParseExpr(astToStr(r))
 			if err != nil {
 				return
@@ -592,6 +1003,50 @@ func (s *sm) walk(a goast.Expr) (c bool, result goast.Expr, _ error) {
 	return false, nil, nil
 }
 
+// Step is step of simplification.
+type Step struct {
+	// Rule is name of simplification rule, for example:
+	// `openParen`, `sort`, `differential`.
+	Rule string
+
+	// Before and After are sub-expression before and after rule.
+	Before, After string
+
+	// Iteration is number of iteration of simplification.
+	Iteration int64
+
+	// Estimate is estimate of absolute error of numeric result for
+	// rule `quadrature`. For other rules it is zero.
+	Estimate float64
+}
+
+// step of simplification by rule
+func (s *sm) step(rule string, before, after goast.Expr) {
+	if s.opts.Trace == nil && s.opts.OnStep == nil {
+		return
+	}
+	s.emit(Step{
+		Rule:      rule,
+		Before:    astToStr(before),
+		After:     astToStr(after),
+		Iteration: s.iter,
+	})
+}
+
+// emit step into trace and callback
+func (s *sm) emit(st Step) {
+	if s.opts.Trace != nil {
+		fmt.Fprintf(s.opts.Trace, "> rule = %s\n", st.Rule)
+		fmt.Fprintf(s.opts.Trace, "> from: %s --->to----> %s\n", st.Before, st.After)
+		if st.Estimate != 0 {
+			fmt.Fprintf(s.opts.Trace, "> error estimate = %g\n", st.Estimate)
+		}
+	}
+	if s.opts.OnStep != nil {
+		s.opts.OnStep(st)
+	}
+}
+
 func (s *sm) inject(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	call, ok := e.(*goast.CallExpr)
 	if !ok {
@@ -604,18 +1059,13 @@ func (s *sm) inject(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if id.Name != injectName {
 		return false, nil, nil
 	}
-	if m, ok := isMatrix(call.Args[0]); ok {
-		for i := range m.Args {
-			m.Args[i] = &goast.CallExpr{
-				Fun: goast.NewIdent(injectName),
-				Args: []goast.Expr{
-					m.Args[i],
-					call.Args[1],
-					call.Args[2],
-				},
-			}
-		}
-		return true, m.ast(), nil
+	if len(call.Args) != 3 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     injectName,
+			Args:     len(call.Args),
+			Expect:   3,
+		})
 	}
 	//
 	// from:
@@ -623,16 +1073,160 @@ func (s *sm) inject(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	// to:
 	// (1.000*1.000/2.000)
 	//
-	body := astToStr(call.Args[0])
-	vars := astToStr(call.Args[1])
-	data := astToStr(call.Args[2])
+	values, err := s.values(call.Args[1:])
+	if err != nil {
+		return false, nil, err
+	}
+	return true, substitute(call.Args[0], values), nil
+}
 
-	a, err := parser.ParseExpr(strings.Replace(body, vars, data, -1))
+func (s *sm) subs(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := e.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != subsName {
+		return false, nil, nil
+	}
+	if len(call.Args) < 3 || len(call.Args)%2 == 0 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     subsName,
+			Args:     len(call.Args),
+			Expect:   3,
+		})
+	}
+	//
+	// from:
+	// subs(x*y, x, 1, y, x)
+	// to:
+	// (1*(x))
+	//
+	values, err := s.values(call.Args[1:])
 	if err != nil {
 		return false, nil, err
 	}
+	return true, substitute(call.Args[0], values), nil
+}
+
+// values return map of names and values from arguments of
+// substitution: name, value, name, value, ...
+func (s *sm) values(args []goast.Expr) (values map[string]goast.Expr, _ error) {
+	values = map[string]goast.Expr{}
+	for i := 0; i+1 < len(args); i += 2 {
+		id, ok := unparen(args[i]).(*goast.Ident)
+		if !ok {
+			return nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(args[i])},
+				Msg:      "not valid name for substitution",
+			})
+		}
+		values[id.Name] = args[i+1]
+	}
+	return
+}
+
+// substitute return expression with names replaced by values. All names
+// are replaced simultaneously. Names of functions are not replaced.
+// Variables of integrals are not replaced in integrand and in bounds of
+// inner integrals, names of inject and subs and name of quadrature
+// scheme are not replaced.
+func substitute(e goast.Expr, values map[string]goast.Expr) goast.Expr {
+	switch v := e.(type) {
+	case *goast.Ident:
+		if value, ok := values[v.Name]; ok {
+			return paren(value)
+		}
+	case *goast.ParenExpr:
+		return &goast.ParenExpr{X: substitute(v.X, values)}
+	case *goast.UnaryExpr:
+		return &goast.UnaryExpr{Op: v.Op, X: substitute(v.X, values)}
+	case *goast.BinaryExpr:
+		return &goast.BinaryExpr{
+			X:  substitute(v.X, values),
+			Op: v.Op,
+			Y:  substitute(v.Y, values),
+		}
+	case *goast.CallExpr:
+		scopes := bindings(v)
+		names := map[int]bool{}
+		for _, scope := range scopes {
+			for _, i := range scope {
+				names[i] = true
+			}
+		}
+		scheme := -1
+		if i, ok := schemeArg(v); ok {
+			scheme = i
+		}
+		call := &goast.CallExpr{Fun: v.Fun}
+		for i := range v.Args {
+			if names[i] || i == scheme {
+				call.Args = append(call.Args, v.Args[i])
+				continue
+			}
+			bound := values
+			if scope := scopes[i]; 0 < len(scope) {
+				bound = map[string]goast.Expr{}
+				for name, value := range values {
+					bound[name] = value
+				}
+				for _, k := range scope {
+					if id, ok := unparen(v.Args[k]).(*goast.Ident); ok {
+						delete(bound, id.Name)
+					}
+				}
+			}
+			call.Args = append(call.Args, substitute(v.Args[i], bound))
+		}
+		return call
+	}
+	return e
+}
+
+// bindings return indexes of arguments with bound names for each
+// argument of call, where that names are bound. For example, in
+// `integral2(f, x, a, b, y, c, d)` names `x`, `y` are bound in `f` and
+// name `x` is bound in `c`, `d`.
+func bindings(call *goast.CallExpr) map[int][]int {
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return nil
+	}
+	scopes := map[int][]int{}
+	switch id.Name {
+	case integralName, nintegralName:
+		// integral(f, x)
+		// integral(f, x, a, b)
+		if len(call.Args) == 2 || 4 <= len(call.Args) {
+			scopes[0] = []int{1}
+		}
+	case integral2, integral3:
+		// integral2(f, x, a, b, y, c, d)
+		for k := 1; k+2 < len(call.Args); k += 3 {
+			scopes[k+1] = append([]int{}, scopes[0]...)
+			scopes[k+2] = append([]int{}, scopes[0]...)
+			scopes[0] = append(scopes[0], k)
+		}
+	case injectName, subsName:
+		// subs(f, x, 1, y, 2)
+		for k := 1; k < len(call.Args); k += 2 {
+			scopes[0] = append(scopes[0], k)
+		}
+	}
+	return scopes
+}
 
-	return true, a, nil
+// matrixShape is check of amount of matrix values
+func (s *sm) matrixShape(e goast.Expr) (changed bool, r goast.Expr, _ error) {
+	if _, _, err := isMatrix(e); err != nil {
+		return false, nil, s.errorGen(err)
+	}
+	return false, nil, nil
 }
 
 func (s *sm) matrixTranspose(e goast.Expr) (changed bool, r goast.Expr, _ error) {
@@ -648,22 +1242,33 @@ func (s *sm) matrixTranspose(e goast.Expr) (changed bool, r goast.Expr, _ error)
 		return false, nil, nil
 	}
 	if len(call.Args) != 1 {
-		panic("this is impossible")
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     transpose,
+			Args:     len(call.Args),
+			Expect:   1,
+		})
+	}
+	mt, ok, err := isMatrix(call.Args[0])
+	if err != nil {
+		return false, nil, s.errorGen(err)
 	}
-	id.Name = "" // TODO : why it is here?
-	mt, ok := isMatrix(call.Args[0])
 	if !ok {
-		panic("not valid transpose matrix")
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "argument of transpose is not matrix",
+		})
 	}
+	id.Name = "" // TODO : why it is here?
 
 	// transpose
-	trans := createMatrix(mt.Cols, mt.Rows)
+	trans := s.createMatrix(mt.Cols, mt.Rows)
 	for r := 0; r < mt.Rows; r++ {
 		for c := 0; c < mt.Cols; c++ {
 			trans.Args[trans.position(c, r)] = mt.Args[mt.position(r, c)]
 		}
 	}
-	return true, trans.ast(), nil
+	return true, s.matrixToAst(trans), nil
 }
 
 func (s *sm) matrixDet(e goast.Expr) (changed bool, r goast.Expr, _ error) {
@@ -679,17 +1284,30 @@ func (s *sm) matrixDet(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, nil
 	}
 	if len(call.Args) != 1 {
-		panic(fmt.Errorf("this is impossible. len = %d", len(call.Args)))
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     det,
+			Args:     len(call.Args),
+			Expect:   1,
+		})
+	}
+	mt, ok, err := isMatrix(call.Args[0])
+	if err != nil {
+		return false, nil, s.errorGen(err)
 	}
-	id.Name = "" // TODO : why it is here?
-	mt, ok := isMatrix(call.Args[0])
 	if !ok {
-		panic("not valid det matrix")
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "argument of det is not matrix",
+		})
 	}
-
 	if mt.Cols != mt.Rows {
-		panic("not square matrix")
+		return false, nil, s.errorGen(&MatrixShapeError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "not square matrix",
+		})
 	}
+	id.Name = "" // TODO : why it is here?
 
 	// matrix 1x1
 	if mt.Cols == 1 && mt.Rows == 1 {
@@ -699,9 +1317,9 @@ func (s *sm) matrixDet(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 	// determinant of matrix
 	var dm goast.Expr
-	dm = createFloat(0.0)
+	dm = s.createFloat(0.0)
 	for i := 0; i < size; i++ {
-		mat := createMatrix(size-1, size-1)
+		mat := s.createMatrix(size-1, size-1)
 		for row := 1; row < size; row++ {
 			for c := 0; c < size-1; c++ {
 				col := c
@@ -714,7 +1332,7 @@ func (s *sm) matrixDet(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 		determinant := &goast.CallExpr{
 			Fun:  goast.NewIdent(det),
-			Args: []goast.Expr{mat.ast()},
+			Args: []goast.Expr{s.matrixToAst(mat)},
 		}
 
 		value := mt.Args[mt.position(0, i)]
@@ -723,7 +1341,7 @@ func (s *sm) matrixDet(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 			dm = &goast.BinaryExpr{
 				X:  dm,
 				Op: token.ADD,
-				Y:  createFloat(0.0),
+				Y:  s.createFloat(0.0),
 			}
 			continue
 		}
@@ -767,22 +1385,35 @@ func (s *sm) matrixInverse(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, nil
 	}
 	if len(call.Args) != 1 {
-		panic("this is impossible")
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     inverse,
+			Args:     len(call.Args),
+			Expect:   1,
+		})
+	}
+	mt, ok, err := isMatrix(call.Args[0])
+	if err != nil {
+		return false, nil, s.errorGen(err)
 	}
-	id.Name = "" // TODO : why it is here?
-	mt, ok := isMatrix(call.Args[0])
 	if !ok {
-		panic("not valid inverse matrix")
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "argument of inverse is not matrix",
+		})
 	}
-
 	if mt.Cols != mt.Rows {
-		panic("not square matrix")
+		return false, nil, s.errorGen(&MatrixShapeError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "not square matrix",
+		})
 	}
+	id.Name = "" // TODO : why it is here?
 	size := mt.Cols
 
 	var value goast.Expr
 	value = &goast.BinaryExpr{
-		X:  createFloat(1.0),
+		X:  s.createFloat(1.0),
 		Op: token.QUO,
 		Y: &goast.CallExpr{
 			Fun:  goast.NewIdent(det),
@@ -800,10 +1431,10 @@ func (s *sm) matrixInverse(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	value = goast.NewIdent("(" + out + ")")
 
 	// prepare of matrix
-	mat := createMatrix(size, size)
+	mat := s.createMatrix(size, size)
 	for r := 0; r < size; r++ {
 		for c := 0; c < size; c++ {
-			part := createMatrix(size-1, size-1)
+			part := s.createMatrix(size-1, size-1)
 			for row := 0; row < size-1; row++ {
 				for col := 0; col < size-1; col++ {
 					row2, col2 := row, col
@@ -817,16 +1448,16 @@ func (s *sm) matrixInverse(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 				}
 			}
 			body := append([]goast.Expr{}, part.Args...)
-			body = append(body, createFloat(size-1))
-			body = append(body, createFloat(size-1))
+			body = append(body, s.createFloat(float64(size-1)))
+			body = append(body, s.createFloat(float64(size-1)))
 			detm := &goast.CallExpr{
 				Fun:  goast.NewIdent(det),
-				Args: []goast.Expr{part.ast()},
+				Args: []goast.Expr{s.matrixToAst(part)},
 			}
 			mat.Args[mat.position(r, c)] = detm
 			if (r+c)%2 != 0 {
 				mat.Args[mat.position(r, c)] = &goast.BinaryExpr{
-					X:  createFloat(-1.0),
+					X:  s.createFloat(-1.0),
 					Op: token.MUL,
 					Y:  mat.Args[mat.position(r, c)],
 				}
@@ -839,7 +1470,7 @@ func (s *sm) matrixInverse(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 		Op: token.MUL,
 		Y: &goast.CallExpr{
 			Fun:  goast.NewIdent(transpose),
-			Args: []goast.Expr{mat.ast()},
+			Args: []goast.Expr{s.matrixToAst(mat)},
 		},
 	}
 	return true, result, nil
@@ -855,11 +1486,17 @@ func (s *sm) matrixSum(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, nil
 	}
 
-	left, ok := isMatrix(bin.X)
+	left, ok, err := isMatrix(bin.X)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
 	if !ok {
 		return false, nil, nil
 	}
-	right, ok := isMatrix(bin.Y)
+	right, ok, err := isMatrix(bin.Y)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
 	if !ok {
 		return false, nil, nil
 	}
@@ -870,7 +1507,7 @@ func (s *sm) matrixSum(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, fmt.Errorf("not valid matrix columns add")
 	}
 
-	result := createMatrix(left.Rows, left.Cols)
+	result := s.createMatrix(left.Rows, left.Cols)
 	for r := 0; r < left.Rows; r++ {
 		for c := 0; c < left.Cols; c++ {
 			pos := left.position(r, c)
@@ -881,7 +1518,7 @@ func (s *sm) matrixSum(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			}
 		}
 	}
-	return true, result.ast(), nil
+	return true, s.matrixToAst(result), nil
 }
 
 func (s *sm) matrixMultiply(a goast.Expr) (changed bool, r goast.Expr, _ error) {
@@ -894,16 +1531,25 @@ func (s *sm) matrixMultiply(a goast.Expr) (changed bool, r goast.Expr, _ error)
 		return false, nil, nil
 	}
 
-	left, ok := isMatrix(bin.X)
+	left, ok, err := isMatrix(bin.X)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
 	if !ok {
 		return false, nil, nil
 	}
-	right, ok := isMatrix(bin.Y)
+	right, ok, err := isMatrix(bin.Y)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
 	if !ok {
 		return false, nil, nil
 	}
 	if left.Cols != right.Rows {
-		return false, nil, fmt.Errorf("not valid matrix multiplication")
+		return false, nil, s.errorGen(&MatrixShapeError{
+			Location: Location{Expr: astToStr(a)},
+			Msg:      "not valid matrix multiplication",
+		})
 	}
 
 	var result matriX
@@ -932,7 +1578,7 @@ func (s *sm) matrixMultiply(a goast.Expr) (changed bool, r goast.Expr, _ error)
 			result.Args = append(result.Args, arg)
 		}
 	}
-	return true, result.ast(), nil
+	return true, s.matrixToAst(&result), nil
 }
 
 func (s *sm) divideDivide(a goast.Expr) (changed bool, r goast.Expr, _ error) {
@@ -1019,11 +1665,18 @@ func (s *sm) divide(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			Y:  rightBin.X,
 		}, nil
 	}
+
+	// from : (x*x-1)/(x-1)
+	// to   : x+1
+	if r, ok := s.cancelQuo(bin.X, bin.Y); ok {
+		return true, r, nil
+	}
+
 	leftBin, ok := bin.X.(*goast.BinaryExpr)
 	if !ok {
 		return false, nil, nil
 	}
-	if leftBin.Op != token.QUO {
+	if leftBin.Op != token.QUO || isFraction(leftBin) {
 		return false, nil, nil
 	}
 
@@ -1060,8 +1713,8 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if bin.Op == token.MUL {
 		// from : (any1/any2) * (any3/any4)
 		// to   : (any1 * any3) / (any2 * any4)
-		if left, ok := bin.X.(*goast.BinaryExpr); ok && left.Op == token.QUO {
-			if right, ok := bin.Y.(*goast.BinaryExpr); ok && right.Op == token.QUO {
+		if left, ok := bin.X.(*goast.BinaryExpr); ok && left.Op == token.QUO && !isFraction(left) {
+			if right, ok := bin.Y.(*goast.BinaryExpr); ok && right.Op == token.QUO && !isFraction(right) {
 				return true, &goast.BinaryExpr{
 					X: &goast.BinaryExpr{
 						X:  left.X,
@@ -1080,7 +1733,7 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 		// from : (any1/any2) * any3
 		// to   : (any1 * any3) / any2
-		if left, ok := bin.X.(*goast.BinaryExpr); ok && left.Op == token.QUO {
+		if left, ok := bin.X.(*goast.BinaryExpr); ok && left.Op == token.QUO && !isFraction(left) {
 			right, ok := bin.Y.(*goast.BinaryExpr)
 			if !ok || (ok && right.Op == token.QUO) {
 				return true, &goast.BinaryExpr{
@@ -1096,7 +1749,7 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		}
 	}
 
-	// 	if q := parseQuoArray(a); 1 < len(ma) {
+	// 	if q := s.parseQuoArray(a); 1 < len(ma) {
 	// 		for i := 1; i < len(ma); i++ {
 	// 			bef, bok := ma[i-1].(*goast.BinaryExpr)
 	// 			pre, pok := ma[i].(*goast.BinaryExpr)
@@ -1113,14 +1766,14 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	// 			ok2, v2 := isNumber(ma[i])
 	// 			if ok1 && ok2 {
 	// 				mt := ma[:i-1]
-	// 				mt = append(mt, createFloat(v1*v2))
+	// 				mt = append(mt, s.createFloat(v1*v2))
 	// 				mt = append(mt, ma[i+1:]...)
 	// 				return true, multiplySlice(mt).toAst(), nil
 	// 			}
 	// 		}
 	// 	}
 
-	q := parseQuoArray(a)
+	q := s.parseQuoArray(a)
 	if 0 < len(q.do) {
 		for ui := range q.up {
 			if len(q.do) == 1 {
@@ -1153,13 +1806,13 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			for i := range ma {
 				if i == 0 {
 					q := quoArray{up: append(common, ma[i].toAst()), do: q.do}
-					result = q.toAst()
+					result = s.quoToAst(q)
 					continue
 				}
 				result = &goast.BinaryExpr{
 					X:  result,
 					Op: token.ADD,
-					Y:  quoArray{up: append(common, ma[i].toAst()), do: q.do}.toAst(),
+					Y:  s.quoToAst(quoArray{up: append(common, ma[i].toAst()), do: q.do}),
 				}
 			}
 			return true, result, nil
@@ -1191,7 +1844,7 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			}
 		}
 		if 0 < amount {
-			return true, q.toAst(), nil
+			return true, s.quoToAst(q), nil
 		}
 	}
 
@@ -1219,24 +1872,24 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	// 			if num != 1.0 && 1 < counter {
 	// 				if len(do) == 0 {
 	// 					if len(up) == 0 {
-	// 						return true, createFloat(num), nil
+	// 						return true, s.createFloat(num), nil
 	// 					}
 	// 					return true, &goast.BinaryExpr{
-	// 						X:  createFloat(num),
+	// 						X:  s.createFloat(num),
 	// 						Op: token.MUL,
 	// 						Y:  up.toAst(),
 	// 					}, nil
 	// 				}
 	// 				if len(up) == 0 {
 	// 					return true, &goast.BinaryExpr{
-	// 						X:  createFloat(num),
+	// 						X:  s.createFloat(num),
 	// 						Op: token.QUO,
 	// 						Y:  do.toAst(),
 	// 					}, nil
 	// 				}
 	// 				return true, &goast.BinaryExpr{
 	// 					X: &goast.BinaryExpr{
-	// 						X:  createFloat(num),
+	// 						X:  s.createFloat(num),
 	// 						Op: token.MUL,
 	// 						Y:  up.toAst(),
 	// 					},
@@ -1259,17 +1912,17 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			if i == 0 {
 				continue
 			}
-			s := []sliceSumm(sum)
-			if bin, ok := s[i].value.(*goast.BinaryExpr); ok && bin.Op == token.MUL {
+			ss := []sliceSumm(sum)
+			if bin, ok := ss[i].value.(*goast.BinaryExpr); ok && bin.Op == token.MUL {
 				if ok, n := isNumber(bin.X); ok && n < 0 {
-					s[i].isNegative = !s[i].isNegative
+					ss[i].isNegative = !ss[i].isNegative
 					if n == 0.0 {
-						s[i].value = createFloat(0)
+						ss[i].value = s.createFloat(0)
 					} else if -n == 1.0 {
-						s[i].value = bin.Y
+						ss[i].value = bin.Y
 					} else {
-						s[i].value = &goast.BinaryExpr{
-							X:  createFloat(-n),
+						ss[i].value = &goast.BinaryExpr{
+							X:  s.createNegative(bin.X),
 							Op: token.MUL,
 							Y:  bin.Y,
 						}
@@ -1279,22 +1932,22 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 					continue
 				}
 			}
-			if bin, ok := s[i].value.(*goast.BinaryExpr); ok && bin.Op == token.QUO {
+			if bin, ok := ss[i].value.(*goast.BinaryExpr); ok && bin.Op == token.QUO {
 				if up, ok := bin.X.(*goast.BinaryExpr); ok && up.Op == token.MUL {
 					if ok, n := isNumber(up.X); ok && n < 0 {
-						s[i].isNegative = !s[i].isNegative
+						ss[i].isNegative = !ss[i].isNegative
 						if n == 0 {
-							s[i].value = createFloat(0)
+							ss[i].value = s.createFloat(0)
 						} else if -n == 1.0 {
-							s[i].value = &goast.BinaryExpr{
+							ss[i].value = &goast.BinaryExpr{
 								X:  up.Y,
 								Op: token.QUO,
 								Y:  bin.Y,
 							}
 						} else {
-							s[i].value = &goast.BinaryExpr{
+							ss[i].value = &goast.BinaryExpr{
 								X: &goast.BinaryExpr{
-									X:  createFloat(-n),
+									X:  s.createNegative(up.X),
 									Op: token.MUL,
 									Y:  up.Y,
 								},
@@ -1315,18 +1968,23 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 		type eqn struct {
 			coeff float64
+			exact *big.Rat // for Exact mode
 			ast   string
 		}
 
 		eqns := make([]eqn, len(sum))
 		for i := range sum {
 			eqns[i].coeff = 1.0
+			eqns[i].exact = big.NewRat(1, 1)
 			if sum[i].isNegative {
 				eqns[i].coeff *= -1.0
+				eqns[i].exact.Neg(eqns[i].exact)
 			}
 			if bin, ok := sum[i].value.(*goast.BinaryExpr); ok && bin.Op == token.MUL {
 				if ok, n := isNumber(bin.X); ok {
+					_, r := isRational(bin.X)
 					eqns[i].coeff *= n
+					eqns[i].exact.Mul(eqns[i].exact, r)
 					eqns[i].ast = astToStr(bin.Y)
 					continue
 				}
@@ -1334,7 +1992,9 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			if bin, ok := sum[i].value.(*goast.BinaryExpr); ok && bin.Op == token.QUO {
 				if left, ok := bin.X.(*goast.BinaryExpr); ok && left.Op == token.MUL {
 					if ok, n := isNumber(left.X); ok {
+						_, r := isRational(left.X)
 						eqns[i].coeff *= n
+						eqns[i].exact.Mul(eqns[i].exact, r)
 						eqns[i].ast = astToStr(&goast.BinaryExpr{
 							X:  left.Y,
 							Op: token.QUO,
@@ -1358,28 +2018,37 @@ func (s *sm) binaryNumber(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 					continue
 				}
 				eqns[i].coeff += eqns[j].coeff
+				eqns[i].exact.Add(eqns[i].exact, eqns[j].exact)
+				if s.opts.Exact {
+					eqns[i].coeff, _ = eqns[i].exact.Float64()
+				}
 				eqns = append(eqns[:j], eqns[j+1:]...)
 				goto again2
 			}
 		}
 		if len(eqns) < size {
-			s := make([]sliceSumm, len(eqns))
+			ss := make([]sliceSumm, len(eqns))
 			for i := range eqns {
 				if eqns[i].coeff == 0 {
-					s[i].value = createFloat(0)
+					ss[i].value = s.createFloat(0)
 					continue
 				}
 				if eqns[i].coeff < 0 {
-					s[i].isNegative = true
+					ss[i].isNegative = true
 					eqns[i].coeff = -eqns[i].coeff
+					eqns[i].exact.Neg(eqns[i].exact)
 				}
-				s[i].value = &goast.BinaryExpr{
-					X:  createFloat(eqns[i].coeff),
+				coeff := s.createFloat(eqns[i].coeff)
+				if s.opts.Exact {
+					coeff = s.createRat(eqns[i].exact)
+				}
+				ss[i].value = &goast.BinaryExpr{
+					X:  coeff,
 					Op: token.MUL,
 					Y:  goast.NewIdent(eqns[i].ast),
 				}
 			}
-			return true, summSlice(s).toAst(), nil
+			return true, summSlice(ss).toAst(), nil
 		}
 	}
 
@@ -1402,7 +2071,9 @@ func (s *sm) oneMul(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 				return true, bin.X, nil
 			}
 			if val == 0.0 {
-				panic("cannot divide by zero")
+				return false, nil, s.errorGen(&DivisionByZeroError{
+					Location: Location{Expr: astToStr(bin)},
+				})
 			}
 		}
 
@@ -1423,7 +2094,7 @@ func (s *sm) oneMul(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 				return true, v.r, nil
 			}
 			if val == 0.0 {
-				return true, createFloat(0), nil
+				return true, s.createFloat(0), nil
 			}
 		}
 	}
@@ -1442,24 +2113,66 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if id.Name != differential {
 		return false, nil, nil
 	}
-	if len(call.Args) != 2 {
-		panic("function pow have not 2 arguments")
-	}
-	id, ok = call.Args[1].(*goast.Ident)
-	if !ok {
-		return false, nil, s.errorGen(fmt.Errorf(
-			"Second argument of differential is not variable"))
-	}
-
-	dvar := id.Name
-	if !s.isVariable(id) {
-		return false, nil, s.errorGen(fmt.Errorf(
-			"Second argument of differential is not initialized like variable"+
-				": `%s`", dvar))
+	if len(call.Args) < 2 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     differential,
+			Args:     len(call.Args),
+			Expect:   2,
+		})
 	}
-
+	if 2 < len(call.Args) {
+		// from:
+		// d(f, x, 2, y)
+		// to:
+		// d(d(d(f, x), x), y)
+		r := call.Args[0]
+		for i := 1; i < len(call.Args); i++ {
+			variable, order := call.Args[i], 1
+			if i+1 < len(call.Args) {
+				if ok, n := isNumber(call.Args[i+1]); ok {
+					if n < 1 || n != float64(int(n)) {
+						return false, nil, s.errorGen(&UnsupportedError{
+							Location: Location{Expr: astToStr(call.Args[i+1])},
+							Msg:      "order of differential is not positive integer",
+						})
+					}
+					order = int(n)
+					i++
+				}
+			}
+			for j := 0; j < order; j++ {
+				r = &goast.CallExpr{
+					Fun:  goast.NewIdent(differential),
+					Args: []goast.Expr{r, variable},
+				}
+			}
+		}
+		return true, r, nil
+	}
+	id, ok = call.Args[1].(*goast.Ident)
+	if !ok {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "Second argument of differential is not variable",
+		})
+	}
+
+	dvar := id.Name
+	if !s.isVariable(id) {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg: fmt.Sprintf("Second argument of differential is not initialized like variable"+
+				": `%s`", dvar),
+		})
+	}
+
 	// d(matrix(...),x)
-	if mt, ok := isMatrix(call.Args[0]); ok {
+	mt, ok, err := isMatrix(call.Args[0])
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
+	if ok {
 		for i := 0; i < len(mt.Args); i++ {
 			mt.Args[i] = &goast.CallExpr{
 				Fun: goast.NewIdent(differential),
@@ -1469,7 +2182,7 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 				},
 			}
 		}
-		return true, mt.ast(), nil
+		return true, s.matrixToAst(mt), nil
 	}
 
 	// d(u + v, x) = d(u,x) + d(v,x)
@@ -1611,43 +2324,115 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		}, nil
 	}
 
-	{
-		val, exp, ok, err := isFunctionPow(call.Args[0])
-		if ok {
-			if err != nil {
-				return false, nil, s.errorGen(err)
+	if val, exp, ok, err := isFunctionPow(call.Args[0]); ok {
+		if err != nil {
+			return false, nil, s.errorGen(err)
+		}
+		var (
+			d = func(e goast.Expr) goast.Expr {
+				return &goast.CallExpr{
+					Fun:  goast.NewIdent(differential),
+					Args: []goast.Expr{e, goast.NewIdent(dvar)},
+				}
+			}
+			mul = func(x, y goast.Expr) goast.Expr {
+				return &goast.BinaryExpr{X: paren(x), Op: token.MUL, Y: paren(y)}
+			}
+			log = func(e goast.Expr) goast.Expr {
+				return &goast.CallExpr{Fun: goast.NewIdent(logName), Args: []goast.Expr{e}}
 			}
+			power = &goast.CallExpr{Fun: goast.NewIdent(pow), Args: []goast.Expr{val, exp}}
+		)
+		switch valConst, expConst := s.independent(val, dvar), s.independent(exp, dvar); {
+		case valConst && expConst:
+			// from:
+			// d(pow(a,b), x)
+			// where a, b is constant or number
+			// to:
+			// 0.000
+			return true, s.createFloat(0), nil
+
+		case expConst:
+			// from:
+			// d(pow(u,a), x)
+			// where a is constant or number
+			// to:
+			// a * pow(u, a-1) * d(u,x)
+			coeff := exp
+			if ok, v := isRational(exp); ok {
+				coeff = s.createRat(v)
+			}
+			r := mul(coeff, &goast.CallExpr{
+				Fun: goast.NewIdent(pow),
+				Args: []goast.Expr{
+					val,
+					&goast.BinaryExpr{
+						X:  paren(exp),
+						Op: token.SUB,
+						Y:  s.createFloat(1.0),
+					},
+				},
+			})
 			if x, ok := val.(*goast.Ident); ok && x.Name == dvar {
-				found := false
-				if ok := s.isConstant(exp); ok {
-					found = true
+				return true, r, nil
+			}
+			return true, mul(r, d(val)), nil
+
+		case valConst:
+			// from:
+			// d(pow(a,v), x)
+			// where a is constant or number
+			// to:
+			// pow(a,v) * log(a) * d(v,x)
+			return true, mul(mul(power, log(val)), d(exp)), nil
+
+		default:
+			// from:
+			// d(pow(u,v), x)
+			// to:
+			// pow(u,v) * (d(v,x) * log(u) + v * d(u,x) / u)
+			return true, mul(power, &goast.BinaryExpr{
+				X:  mul(d(exp), log(val)),
+				Op: token.ADD,
+				Y: &goast.BinaryExpr{
+					X:  mul(exp, d(val)),
+					Op: token.QUO,
+					Y:  paren(val),
+				},
+			}), nil
+		}
+	}
+	{
+		// from:
+		// d(sin(u), x)
+		// where u is any
+		// to:
+		// cos(u) * d(u, x)
+		if f, ok := call.Args[0].(*goast.CallExpr); ok {
+			if name, ok := f.Fun.(*goast.Ident); ok && isElementary(name.Name) {
+				if len(f.Args) != 1 {
+					return false, nil, s.errorGen(&ArityError{
+						Location: Location{Expr: astToStr(f)},
+						Name:     name.Name,
+						Args:     len(f.Args),
+						Expect:   1,
+					})
 				}
-				if ok, _ := isNumber(exp); ok {
-					found = true
+				df, err := s.derivative(name.Name, f.Args[0])
+				if err != nil {
+					return false, nil, err
 				}
-
-				if found {
-					// from:
-					// d(pow(x,a), x)
-					// where a is constant or number
-					// to:
-					// a * pow(x, a-1)
-					return true, &goast.BinaryExpr{
-						X:  exp,
-						Op: token.MUL,
-						Y: &goast.CallExpr{
-							Fun: goast.NewIdent(pow),
-							Args: []goast.Expr{
-								goast.NewIdent(dvar),
-								&goast.BinaryExpr{
-									X:  exp,
-									Op: token.SUB,
-									Y:  createFloat("1.000"),
-								},
-							},
+				return true, &goast.BinaryExpr{
+					X:  paren(df),
+					Op: token.MUL,
+					Y: &goast.CallExpr{
+						Fun: goast.NewIdent(differential),
+						Args: []goast.Expr{
+							f.Args[0],
+							goast.NewIdent(dvar),
 						},
-					}, nil
-				}
+					},
+				}, nil
 			}
 		}
 	}
@@ -1658,7 +2443,7 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// 0.000
 		num, _ := isNumber(call.Args[0])
 		if num {
-			return true, createFloat("0"), nil
+			return true, s.createFloat(0), nil
 		}
 	}
 	{
@@ -1668,7 +2453,7 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// 1.000
 		if x, ok := call.Args[0].(*goast.Ident); ok {
 			if x.Name == dvar {
-				return true, createFloat("1"), nil
+				return true, s.createFloat(1), nil
 			}
 		}
 	}
@@ -1678,7 +2463,7 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// to:
 		// constant * d(1.000,x)
 		if s.isConstant(call.Args[0]) {
-			call.Args[0] = createFloat("1")
+			call.Args[0] = s.createFloat(1)
 			return true, &goast.BinaryExpr{
 				X:  call.Args[0],
 				Op: token.MUL,
@@ -1693,7 +2478,7 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// 0.000
 		if id, ok := call.Args[0].(*goast.Ident); ok {
 			if ok := s.isFunction(id.Name, dvar); !ok {
-				return true, createFloat("0.0"), nil
+				return true, s.createFloat(0.0), nil
 			}
 		}
 	}
@@ -1701,6 +2486,224 @@ func (s *sm) differential(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	return false, nil, nil
 }
 
+// differentialMatrix is matrix of differentials:
+//
+//	gradient(f, x, y)             : matrix(d(f,x), d(f,y), 2, 1)
+//	hessian(f, x, y)              : matrix(d(f,x,x), d(f,x,y), d(f,y,x), d(f,y,y), 2, 2)
+//	jacobian(matrix(f,g,2,1), x, y) : matrix(d(f,x), d(f,y), d(g,x), d(g,y), 2, 2)
+func (s *sm) differentialMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := a.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok {
+		return false, nil, nil
+	}
+	if id.Name != hessian && id.Name != gradient && id.Name != jacobian {
+		return false, nil, nil
+	}
+	if len(call.Args) < 2 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     id.Name,
+			Args:     len(call.Args),
+			Expect:   2,
+		})
+	}
+	d := func(args ...goast.Expr) goast.Expr {
+		return &goast.CallExpr{Fun: goast.NewIdent(differential), Args: args}
+	}
+	f, vars := call.Args[0], call.Args[1:]
+
+	// functions
+	fs := []goast.Expr{f}
+	mt, ok, err := isMatrix(f)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
+	if ok {
+		if id.Name != jacobian {
+			return false, nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(call)},
+				Msg:      fmt.Sprintf("function `%s` is not defined for matrix", id.Name),
+			})
+		}
+		fs = mt.Args
+	}
+
+	var m *matriX
+	switch id.Name {
+	case gradient:
+		m = s.createMatrix(len(vars), 1)
+		for i := range vars {
+			m.Args[i] = d(f, vars[i])
+		}
+	case hessian:
+		m = s.createMatrix(len(vars), len(vars))
+		for i := range vars {
+			for j := range vars {
+				m.Args[m.position(i, j)] = d(f, vars[i], vars[j])
+			}
+		}
+	case jacobian:
+		m = s.createMatrix(len(fs), len(vars))
+		for i := range fs {
+			for j := range vars {
+				m.Args[m.position(i, j)] = d(fs[i], vars[j])
+			}
+		}
+	}
+	return true, s.matrixToAst(m), nil
+}
+
+// independent return true, if expression is not depend on variable
+func (s sm) independent(e goast.Expr, dvar string) bool {
+	switch v := e.(type) {
+	case *goast.Ident:
+		return v.Name != dvar && !s.isFunction(v.Name, dvar)
+	case *goast.BasicLit:
+		return true
+	case *goast.ParenExpr:
+		return s.independent(v.X, dvar)
+	case *goast.UnaryExpr:
+		return s.independent(v.X, dvar)
+	case *goast.BinaryExpr:
+		return s.independent(v.X, dvar) && s.independent(v.Y, dvar)
+	case *goast.CallExpr:
+		for i := range v.Args {
+			if !s.independent(v.Args[i], dvar) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// derivative return derivative of elementary function by argument `u`
+func (s *sm) derivative(name string, u goast.Expr) (goast.Expr, error) {
+	call := func(name string, arg goast.Expr) goast.Expr {
+		return &goast.CallExpr{Fun: goast.NewIdent(name), Args: []goast.Expr{arg}}
+	}
+	square := func(e goast.Expr) goast.Expr {
+		return &goast.BinaryExpr{X: paren(e), Op: token.MUL, Y: paren(e)}
+	}
+	quo := func(e goast.Expr) goast.Expr {
+		return &goast.BinaryExpr{X: s.createFloat(1), Op: token.QUO, Y: paren(e)}
+	}
+	switch name {
+	case sinName:
+		// cos(u)
+		return call(cosName, u), nil
+	case cosName:
+		// -sin(u)
+		return &goast.UnaryExpr{Op: token.SUB, X: call(sinName, u)}, nil
+	case tanName:
+		// 1/(cos(u)*cos(u))
+		return quo(square(call(cosName, u))), nil
+	case expName:
+		// exp(u)
+		return call(expName, u), nil
+	case logName:
+		// 1/u
+		return quo(u), nil
+	case sqrtName:
+		// 1/(2*sqrt(u))
+		return quo(&goast.BinaryExpr{X: s.createFloat(2), Op: token.MUL, Y: call(sqrtName, u)}), nil
+	case asinName:
+		// 1/sqrt(1-u*u)
+		return quo(call(sqrtName, &goast.BinaryExpr{X: s.createFloat(1), Op: token.SUB, Y: square(u)})), nil
+	case acosName:
+		// -1/sqrt(1-u*u)
+		d, err := s.derivative(asinName, u)
+		if err != nil {
+			return nil, err
+		}
+		return &goast.UnaryExpr{Op: token.SUB, X: paren(d)}, nil
+	case atanName:
+		// 1/(1+u*u)
+		return quo(&goast.BinaryExpr{X: s.createFloat(1), Op: token.ADD, Y: square(u)}), nil
+	case sinhName:
+		// cosh(u)
+		return call(coshName, u), nil
+	case coshName:
+		// sinh(u)
+		return call(sinhName, u), nil
+	case tanhName:
+		// 1/(cosh(u)*cosh(u))
+		return quo(square(call(coshName, u))), nil
+	case absName:
+		// u/abs(u)
+		return &goast.BinaryExpr{X: paren(u), Op: token.QUO, Y: call(absName, u)}, nil
+	}
+	return nil, s.errorGen(&UnsupportedError{
+		Location: Location{Expr: astToStr(callOf(name, u))},
+		Msg:      "not elementary function",
+	})
+}
+
+// elementary is simplification of elementary functions
+func (s *sm) elementary(a goast.Expr) (changed bool, r goast.Expr, _ error) {
+	call, ok := a.(*goast.CallExpr)
+	if !ok {
+		return false, nil, nil
+	}
+	id, ok := call.Fun.(*goast.Ident)
+	if !ok || !isElementary(id.Name) {
+		return false, nil, nil
+	}
+	if len(call.Args) != 1 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     id.Name,
+			Args:     len(call.Args),
+			Expect:   1,
+		})
+	}
+
+	// from:
+	// exp(log(u))
+	// log(exp(u))
+	// to:
+	// u
+	if inner, ok := unparen(call.Args[0]).(*goast.CallExpr); ok && len(inner.Args) == 1 {
+		if f, ok := inner.Fun.(*goast.Ident); ok &&
+			(id.Name == expName && f.Name == logName ||
+				id.Name == logName && f.Name == expName) {
+			return true, inner.Args[0], nil
+		}
+	}
+
+	// abs(-2) = 2
+	if id.Name == absName {
+		if ok, v := isRational(call.Args[0]); ok {
+			return true, s.createRat(v.Abs(v)), nil
+		}
+	}
+
+	// values in special points, for example:
+	// sin(0) = 0
+	// cos(0) = 1
+	ok, v := isNumber(call.Args[0])
+	if !ok {
+		return false, nil, nil
+	}
+	switch {
+	case v == 0 && (id.Name == sinName || id.Name == tanName || id.Name == sqrtName ||
+		id.Name == asinName || id.Name == atanName || id.Name == sinhName ||
+		id.Name == tanhName):
+		return true, s.createFloat(0), nil
+	case v == 0 && (id.Name == cosName || id.Name == expName || id.Name == coshName):
+		return true, s.createFloat(1), nil
+	case v == 1 && (id.Name == logName || id.Name == acosName):
+		return true, s.createFloat(0), nil
+	case v == 1 && id.Name == sqrtName:
+		return true, s.createFloat(1), nil
+	}
+	return false, nil, nil
+}
+
 func isFunctionPow(a goast.Expr) (val, exp goast.Expr, ok bool, err error) {
 	call, ok := a.(*goast.CallExpr)
 	if !ok {
@@ -1714,12 +2717,73 @@ func isFunctionPow(a goast.Expr) (val, exp goast.Expr, ok bool, err error) {
 		return nil, nil, false, nil
 	}
 	if len(call.Args) != 2 {
-		return nil, nil, true, fmt.Errorf("function pow have not 2 arguments")
+		return nil, nil, true, &ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     pow,
+			Args:     len(call.Args),
+			Expect:   2,
+		}
 	}
 
 	return call.Args[0], call.Args[1], true, nil
 }
 
+// rootOf return power of number with not integer exponent, if result
+// is real. In Exact mode result is only rational number, for example
+// `pow(4,1/2)` is `2`, but `pow(2,1/2)` is not calculated.
+func (s *sm) rootOf(base, exp *big.Rat) (r goast.Expr, ok bool) {
+	if !s.opts.Exact {
+		b, _ := base.Float64()
+		e, _ := exp.Float64()
+		v := math.Pow(b, e)
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		return s.createFloat(v), true
+	}
+	if base.Sign() == 0 && 0 < exp.Sign() {
+		return s.createFloat(0), true
+	}
+	if base.Sign() <= 0 || !exp.Denom().IsInt64() || !exp.Num().IsInt64() {
+		return nil, false
+	}
+	// only roots of small degree, for example square and cube roots
+	degree := exp.Denom().Int64()
+	if 64 < degree {
+		return nil, false
+	}
+	// root return integer root of integer value
+	root := func(v *big.Int) (*big.Int, bool) {
+		f, _ := new(big.Float).SetInt(v).Float64()
+		guess := big.NewInt(int64(math.Round(math.Pow(f, 1/float64(degree)))))
+		for _, d := range []int64{0, -1, 1} {
+			g := new(big.Int).Add(guess, big.NewInt(d))
+			if new(big.Int).Exp(g, big.NewInt(degree), nil).Cmp(v) == 0 {
+				return g, true
+			}
+		}
+		return nil, false
+	}
+	num, okn := root(base.Num())
+	den, okd := root(base.Denom())
+	if !okn || !okd {
+		return nil, false
+	}
+	n := exp.Num().Int64()
+	if n < 0 {
+		num, den, n = den, num, -n
+	}
+	if 1<<16 < int64(num.BitLen()+den.BitLen())*n {
+		// too big number
+		return nil, false
+	}
+	v := new(big.Rat).SetFrac(
+		new(big.Int).Exp(num, big.NewInt(n), nil),
+		new(big.Int).Exp(den, big.NewInt(n), nil),
+	)
+	return s.createRat(v), true
+}
+
 func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	val, exp, ok, err := isFunctionPow(a)
 	if !ok {
@@ -1729,6 +2793,17 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, s.errorGen(err)
 	}
 
+	// from : pow(4.000, 0.500)
+	// to   : 2.000
+	if okv, v := isRational(val); okv {
+		if oke, e := isRational(exp); oke && !e.IsInt() {
+			if r, ok := s.rootOf(v, e); ok {
+				return true, r, nil
+			}
+			return false, nil, nil
+		}
+	}
+
 	e, ok := exp.(*goast.BasicLit)
 	if !ok {
 		return false, nil, nil
@@ -1736,7 +2811,10 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 	exponent, err := strconv.ParseFloat(e.Value, 64)
 	if err != nil {
-		panic(err)
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: e.Value},
+			Msg:      fmt.Sprintf("not valid exponent: %v", err),
+		})
 	}
 
 	if exponent != float64(int64(exponent)) {
@@ -1750,7 +2828,7 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// pow(..., 0)
 		// to:
 		// 1
-		return true, createFloat("1"), nil
+		return true, s.createFloat(1), nil
 	}
 
 	if exn == 1 {
@@ -1763,18 +2841,41 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		// from : pow(...,-5)
 		// to   : 1/pow(...,5)
 		return true, &goast.BinaryExpr{
-			X:  createFloat(1),
+			X:  s.createFloat(1),
 			Op: token.QUO,
 			Y: &goast.CallExpr{
 				Fun: goast.NewIdent(pow),
 				Args: []goast.Expr{
 					val,
-					createFloat(fmt.Sprintf("%d", -exn)),
+					s.createFloat(float64(-exn)),
 				},
 			},
 		}, nil
 	}
 
+	if base, n, ok := repeated(val); ok && foldable(base) {
+		// from : pow(pow(L,2),3)
+		// to   : pow(L,6)
+		n = new(big.Int).Mul(n, big.NewInt(exn))
+		return true, callOf(pow, base, s.createRat(new(big.Rat).SetInt(n))), nil
+	}
+	q := s.parseQuoArray(val)
+	if 1 < len(q.up)+len(q.do) && !hasMatrix(q) {
+		// from : pow(a*b/c,3)
+		// to   : pow(a,3)*pow(b,3)/pow(c,3)
+		for _, fs := range [][]goast.Expr{q.up, q.do} {
+			for i := range fs {
+				fs[i] = callOf(pow, fs[i], s.createFloat(float64(exn)))
+			}
+		}
+		return true, s.quoToAst(q), nil
+	}
+	if len(q.up) == 1 && len(q.do) == 0 &&
+		len(parseSummArray(q.up[0])) == 1 && foldable(q.up[0]) {
+		// power of single factor is canonical form, for example: pow(L,6)
+		return false, nil, nil
+	}
+
 	if exn%2 == 0 {
 		// from : pow(...,4)
 		// to   : pow(...,2)*pow(...,2)
@@ -1783,7 +2884,7 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			Fun: goast.NewIdent(pow),
 			Args: []goast.Expr{
 				val,
-				createFloat(fmt.Sprintf("%d", exn/2)),
+				s.createFloat(float64(exn / 2)),
 			},
 		})
 		out, err := copy.run()
@@ -1806,14 +2907,14 @@ func (s *sm) functionPow(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			Fun: goast.NewIdent(pow),
 			Args: []goast.Expr{
 				val,
-				createFloat(fmt.Sprintf("%d", exn-1)),
+				s.createFloat(float64(exn - 1)),
 			},
 		},
 	}, nil
 }
 
 func (s *sm) openParen(a goast.Expr) (changed bool, r goast.Expr, _ error) {
-	ma := parseQuoArray(a)
+	ma := s.parseQuoArray(a)
 	if len(ma.up) < 2 {
 		return false, nil, nil
 	}
@@ -1839,7 +2940,7 @@ func (s *sm) openParen(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 
 	results := make([]goast.Expr, size)
 	for ir := range results {
-		results[ir] = createFloat(1)
+		results[ir] = s.createFloat(1)
 	}
 	repeat := size
 	for i := range ss {
@@ -1862,7 +2963,7 @@ func (s *sm) openParen(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		var q quoArray
 		q.up = []goast.Expr{results[i]}
 		q.do = do
-		results[i] = q.toAst()
+		results[i] = s.quoToAst(q)
 	}
 	r, err := s.summOfParts(results)
 	if err != nil {
@@ -1893,6 +2994,7 @@ func (s *sm) summOfParts(ps []goast.Expr) (r goast.Expr, _ error) {
 		middle := len(ps) / 2
 		var rs [2]goast.Expr
 		var errs [2]error
+		var cs [2]sm
 		var wg sync.WaitGroup
 		wg.Add(2)
 		for _, v := range []struct {
@@ -1904,17 +3006,19 @@ func (s *sm) summOfParts(ps []goast.Expr) (r goast.Expr, _ error) {
 		} {
 			index := v.index
 			ps := v.ps
+			cs[index] = s.copy()
 			go func(i int, ps []goast.Expr) {
-				rs[i], errs[i] = s.summOfParts(ps)
+				rs[i], errs[i] = cs[i].summOfParts(ps)
 				wg.Done()
 			}(index, ps)
 		}
 		wg.Wait()
+		s.iter += cs[0].iter + cs[1].iter
 		if errs[0] != nil {
 			return nil, errs[0]
 		}
 		if errs[1] != nil {
-			return nil, errs[0]
+			return nil, errs[1]
 		}
 		return s.summOfParts([]goast.Expr{rs[0], rs[1]})
 	}
@@ -2007,12 +3111,12 @@ func (s *sm) zeroValueMul(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	// any * zero
 	// zero * any
 	if bin.Op == token.MUL && (isZero(bin.X) || isZero(bin.Y)) {
-		return true, createFloat(0.0), nil
+		return true, s.createFloat(0.0), nil
 	}
 
 	// zero / any
 	if bin.Op == token.QUO && isZero(bin.X) {
-		return true, createFloat(0.0), nil
+		return true, s.createFloat(0.0), nil
 	}
 
 	return false, nil, nil
@@ -2030,19 +3134,35 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if id.Name != integralName {
 		return false, nil, nil
 	}
-	if len(call.Args) != 4 {
-		panic("not valid integral")
+	if len(call.Args) < 2 || 5 < len(call.Args) || len(call.Args) == 3 {
+		return false, nil, s.errorGen(&ArityError{
+			Location: Location{Expr: astToStr(call)},
+			Name:     integralName,
+			Args:     len(call.Args),
+			Expect:   4,
+		})
 	}
 
+	// integral(f, x) is indefinite integral
+	// integral(f, x, begin, finish) is definite integral
+	// integral(f, x, begin, finish, gauss2) is quadrature by scheme
 	var (
 		function = call.Args[0]
 		variable = call.Args[1]
-		begin    = call.Args[2]
-		finish   = call.Args[3]
+		bounds   = call.Args[2:]
 	)
+	integral := func(f goast.Expr) goast.Expr {
+		return &goast.CallExpr{
+			Fun:  goast.NewIdent(integralName),
+			Args: append([]goast.Expr{f, variable}, bounds...),
+		}
+	}
 
 	if !s.isVariable(variable) {
-		return false, nil, fmt.Errorf("Variable of integral is not variable: %s", astToStr(variable))
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      fmt.Sprintf("Variable of integral is not variable: %s", astToStr(variable)),
+		})
 	}
 
 	// integral(...+...)
@@ -2050,14 +3170,7 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if summ := parseSummArray(function); 1 < len(summ) {
 		var results []goast.Expr
 		for i := range summ {
-			results = append(results, &goast.CallExpr{
-				Fun: goast.NewIdent(integralName),
-				Args: []goast.Expr{
-					summ[i].toAst(),
-					variable,
-					begin,
-					finish,
-				}})
+			results = append(results, integral(summ[i].toAst()))
 		}
 
 		r, err := s.summOfParts(results)
@@ -2068,17 +3181,36 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	}
 
 	// integral(matrix(...),x,0,1)
-	if mt, ok := isMatrix(function); ok {
+	mt, ok, err := isMatrix(function)
+	if err != nil {
+		return false, nil, s.errorGen(err)
+	}
+	if ok {
 		for i := 0; i < len(mt.Args); i++ {
-			mt.Args[i] = &goast.CallExpr{
-				Fun: goast.NewIdent(integralName),
-				Args: []goast.Expr{
-					mt.Args[i],
-					variable, begin, finish,
-				},
-			}
+			mt.Args[i] = integral(mt.Args[i])
 		}
-		return true, mt.ast(), nil
+		return true, s.matrixToAst(mt), nil
+	}
+
+	// integral(f, x, 0, 1, gauss2)
+	if len(bounds) == 3 {
+		sc, err := s.scheme(call, bounds[2])
+		if err != nil {
+			return false, nil, err
+		}
+		if sc.triangle {
+			return false, nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(call)},
+				Msg:      fmt.Sprintf("scheme `%s` is valid only for %s", astToStr(bounds[2]), integral2),
+			})
+		}
+		r, err := s.exactly(func(c *sm) (goast.Expr, error) {
+			return c.gaussPoints(sc, function, variable, bounds[0], bounds[1])
+		})
+		if err != nil {
+			return false, nil, err
+		}
+		return true, r, nil
 	}
 
 	// extract constansts:
@@ -2094,41 +3226,12 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	//	integral(a / ... , ...)
 	// to:
 	//	a * integral(1.000 / ... , ...)
-	var possibleExtract func(e goast.Expr) (result bool)
-	possibleExtract = func(e goast.Expr) (result bool) {
-		//	defer func(){
-		//		fmt.Println(	">>", astToStr(e), result)
-		//	}()
-		// constants or numbers
-		if ok, v := isNumber(e); ok && v != 1.0 {
-			return true
-		}
-		if s.isConstant(e) {
-			return true
-		}
-		// trigonometric of constants or numbers
-		if call, ok := e.(*goast.CallExpr); ok {
-			ok = false
-			for _, name := range []string{sinName, cosName, tanName} {
-				var id *goast.Ident
-				id, ok = call.Fun.(*goast.Ident)
-				if !ok {
-					continue
-				}
-				if id.Name != name {
-					return true
-				}
-			}
+	possibleExtract := func(e goast.Expr) (result bool) {
+		// numbers, constants and expressions independent of variable
+		if ok, v := isNumber(e); ok {
+			return v != 1.0
 		}
-		// number/number
-		// constants/constants
-		if bin, ok := e.(*goast.BinaryExpr); ok {
-			if possibleExtract(bin.X) && possibleExtract(bin.Y) {
-				return true
-			}
-		}
-
-		return false
+		return s.independent(e, astToStr(variable))
 	}
 
 	// from:
@@ -2136,7 +3239,7 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 	// to:
 	// a*integral(...)
 	{
-		q := parseQuoArray(function)
+		q := s.parseQuoArray(function)
 		var (
 			coeff      goast.Expr = goast.NewIdent("1.000")
 			addedCoeff bool       = false
@@ -2164,7 +3267,7 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 				X:  coeff,
 				Op: token.MUL,
 				Y: &goast.BinaryExpr{
-					X:  createFloat(1),
+					X:  s.createFloat(1),
 					Op: token.QUO,
 					Y:  q.do[i],
 				},
@@ -2173,29 +3276,10 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 			goto again
 		}
 		if addedCoeff {
-			if len(q.up) == 0 && len(q.do) == 0 {
-				return true, &goast.BinaryExpr{
-					X:  coeff,
-					Op: token.MUL,
-					Y: &goast.CallExpr{
-						Fun: goast.NewIdent(integralName),
-						Args: []goast.Expr{
-							createFloat(1),
-							variable, begin, finish,
-						},
-					},
-				}, nil
-			}
 			return true, &goast.BinaryExpr{
 				X:  coeff,
 				Op: token.MUL,
-				Y: &goast.CallExpr{
-					Fun: goast.NewIdent(integralName),
-					Args: []goast.Expr{
-						q.toAst(),
-						variable, begin, finish,
-					},
-				},
+				Y:  integral(s.quoToAst(q)),
 			}, nil
 			// 			if len(q.up) == 0 {
 			// 				return true, &goast.BinaryExpr{
@@ -2205,7 +3289,7 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 			// 						Fun: goast.NewIdent(integralName),
 			// 						Args: []goast.Expr{
 			// 							&goast.BinaryExpr{
-			// 								X:  createFloat(1),
+			// 								X:  s.createFloat(1),
 			// 								Op: token.QUO,
 			// 								Y:  q.do.toAst(),
 			// 							},
@@ -2245,70 +3329,67 @@ func (s *sm) integral(e goast.Expr) (changed bool, r goast.Expr, _ error) {
 		}
 	}
 
+	// indefinite integral by table of antiderivatives
+	if len(bounds) == 0 {
+		if r, ok := s.integrate(function, variable); ok {
+			return true, r, nil
+		}
+		// rational function by partial fractions
+		r, ok, err := s.integrateRational(function, variable)
+		if err != nil || !ok {
+			return false, nil, err
+		}
+		return true, r, nil
+	}
+
+	// definite integral by antiderivative
 	//
-	// d(pow(x,n+1)/(n+1), 0.000, 1.000)
-	//
-	// n = 1
 	// integral(x, x, 0.000, 1.000)
-	// inject(pow(x,1+1)/(1+1), x, 1) - inject(pow(x,1+1)/(1+1), x, 0)
+	// inject(0.500*(x*x), x, 1.000) - inject(0.500*(x*x), x, 0.000)
 	//
-	{
-		body := astToStr(function)
-		n := float64(strings.Count(body, astToStr(variable)))
-		body = strings.Replace(body, astToStr(variable), "", -1)
-		body = strings.Replace(body, "(", "", -1)
-		body = strings.Replace(body, ")", "", -1)
-		body = strings.Replace(body, "*", "", -1)
-		body = strings.TrimSpace(body)
-
-		if body == "" {
-			power := &goast.CallExpr{
-				Fun: goast.NewIdent("pow"),
-				Args: []goast.Expr{
-					variable,
-					createFloat(fmt.Sprintf("%15e", n+1.0)),
-				},
-			}
-			div := &goast.BinaryExpr{
-				X:  power,
-				Op: token.QUO,
-				Y:  createFloat(fmt.Sprintf("%15e", n+1.0)),
-			}
-			return true, &goast.BinaryExpr{
-				X: &goast.CallExpr{
-					Fun: goast.NewIdent("inject"),
-					Args: []goast.Expr{
-						div,
-						variable,
-						finish,
-					},
-				},
-				Op: token.SUB,
-				Y: &goast.CallExpr{
-					Fun: goast.NewIdent("inject"),
-					Args: []goast.Expr{
-						div,
-						variable,
-						begin,
-					},
-				},
-			}, nil
-		}
-
-		if n == 0 {
-			return true, &goast.BinaryExpr{
-				X:  function,
-				Op: token.MUL,
-				Y: &goast.BinaryExpr{
-					X:  finish,
-					Op: token.SUB,
-					Y:  begin,
-				},
-			}, nil
+	// and difference is simplified
+	antiderivative, ok, err := s.antiderivative(function, variable)
+	if err != nil {
+		return false, nil, err
+	}
+	singular, err := s.singular(function, antiderivative, variable, bounds[0], bounds[1])
+	if err != nil {
+		return false, nil, err
+	}
+	if singular {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "integrand is not finite between bounds",
+		})
+	}
+	if !ok {
+		// numeric integration for numeric bounds and integrand
+		// without names, except variable
+		//
+		// integral(exp(-x*x), x, 0, 1)
+		// nintegral(exp(-x*x), x, 0, 1)
+		//
+		id, ok := unparen(variable).(*goast.Ident)
+		if !ok || !numeric(function, id.Name) ||
+			!numeric(bounds[0], "") || !numeric(bounds[1], "") {
+			// closed form and numeric value are not found
+			return false, nil, nil
 		}
+		return true, callOf(nintegralName, function, variable, bounds[0], bounds[1]), nil
 	}
-
-	return false, nil, nil
+	upper, err := s.simplify(callOf(injectName, antiderivative, variable, bounds[1]))
+	if err != nil {
+		return false, nil, err
+	}
+	lower, err := s.simplify(callOf(injectName, antiderivative, variable, bounds[0]))
+	if err != nil {
+		return false, nil, err
+	}
+	r, err = s.simplify(s.difference(upper, lower))
+	if err != nil {
+		return false, nil, err
+	}
+	return true, r, nil
 }
 
 func (s *sm) mulConstToMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error) {
@@ -2317,15 +3398,20 @@ func (s *sm) mulConstToMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error
 		return false, nil, nil
 	}
 	if v.Op == token.QUO {
-		_, ok = isMatrix(v.Y)
-		if ok {
-			panic("not valid matrix quo")
+		_, ok, err := isMatrix(v.Y)
+		if err != nil {
+			return false, nil, s.errorGen(err)
 		}
-		ok = isTranspose(v.Y)
-		if ok {
-			panic("not valid transpose quo")
+		if ok || isTranspose(v.Y) {
+			return false, nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: astToStr(v)},
+				Msg:      "division by matrix",
+			})
+		}
+		mt, ok, err := isMatrix(v.X)
+		if err != nil {
+			return false, nil, s.errorGen(err)
 		}
-		mt, ok := isMatrix(v.X)
 		if !ok {
 			return false, nil, nil
 		}
@@ -2336,7 +3422,7 @@ func (s *sm) mulConstToMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error
 				Y:  v.Y,
 			}
 		}
-		return true, mt.ast(), nil
+		return true, s.matrixToAst(mt), nil
 	}
 
 	if v.Op != token.MUL {
@@ -2346,18 +3432,21 @@ func (s *sm) mulConstToMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error
 	value, matExpr := v.X, v.Y
 	for i := 0; i < 2; i++ {
 		value, matExpr = matExpr, value
-		if _, ok := isMatrix(matExpr); !ok {
+		mt, ok, err := isMatrix(matExpr)
+		if err != nil {
+			return false, nil, s.errorGen(err)
+		}
+		if !ok {
 			continue
 		}
 		if ok := isTranspose(value); ok {
 			continue
 		}
-		if _, ok := isMatrix(value); ok {
-			continue
+		_, ok, err = isMatrix(value)
+		if err != nil {
+			return false, nil, s.errorGen(err)
 		}
-
-		mt, ok := isMatrix(matExpr)
-		if !ok {
+		if ok {
 			continue
 		}
 		for i := 0; i < len(mt.Args); i++ {
@@ -2367,25 +3456,26 @@ func (s *sm) mulConstToMatrix(a goast.Expr) (changed bool, r goast.Expr, _ error
 				Y:  value,
 			}
 		}
-		return true, mt.ast(), nil
+		return true, s.matrixToAst(mt), nil
 	}
 
 	return false, nil, nil
 }
 
-var sortCounter [5]int
-
 func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
-	// defer func(){
-	// 	fmt.Println("sort counter: ", sortCounter)
-	// }()
 	if summ := parseSummArray(a); 0 < len(summ) {
 		{
 			sort := func(es []goast.Expr) (changed bool) {
 				amount := 0
 				estr := make([]string, len(es))
+				bases := make([]goast.Expr, len(es))
 				for i := range es {
-					estr[i] = astToStr(es[i])
+					// power is placed by base, for example: pow(a,2)*b
+					bases[i] = es[i]
+					if base, _, ok := repeated(es[i]); ok {
+						bases[i] = unparen(base)
+					}
+					estr[i] = astToStr(bases[i])
 				}
 			again:
 				runAgain := false
@@ -2393,15 +3483,17 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 					if ok, _ := isNumber(es[i-1]); ok {
 						continue
 					}
-					if !s.isConstant(es[i-1]) && s.isConstant(es[i]) {
+					if !s.isConstant(bases[i-1]) && s.isConstant(bases[i]) {
 						es[i-1], es[i] = es[i], es[i-1]
+						bases[i-1], bases[i] = bases[i], bases[i-1]
 						estr[i-1], estr[i] = estr[i], estr[i-1]
 						amount++
 						runAgain = true
 					}
-					if s.isConstant(es[i-1]) && s.isConstant(es[i]) {
+					if s.isConstant(bases[i-1]) && s.isConstant(bases[i]) {
 						if estr[i-1] > estr[i] {
 							es[i-1], es[i] = es[i], es[i-1]
+							bases[i-1], bases[i] = bases[i], bases[i-1]
 							estr[i-1], estr[i] = estr[i], estr[i-1]
 							amount++
 							runAgain = true
@@ -2418,32 +3510,36 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			}
 			amount := 0
 			for i := range summ {
-				q := parseQuoArray(summ[i].value)
+				q := s.parseQuoArray(summ[i].value)
 				u, d := sort(q.up), sort(q.do)
 				if u || d {
-					summ[i].value = q.toAst()
+					summ[i].value = s.quoToAst(q)
 					amount++
 				}
 			}
 			if 0 < amount {
-				sortCounter[0]++
 				return true, summ.toAst(), nil
 			}
 		}
 		{
 			amountgl := 0
 			for i := range summ {
-				q := parseQuoArray(summ[i].value)
+				q := s.parseQuoArray(summ[i].value)
 
 				var firstNumber bool
 				if 0 < len(q.up) {
 					firstNumber, _ = isNumber(q.up[0])
 				}
 				var numbers float64 = 1
+				exact := big.NewRat(1, 1) // for Exact mode
 				amount := 0
 				for i := 0; i < len(q.up); i++ {
 					if ok, n := isNumber(q.up[i]); ok {
 						numbers *= n
+						if s.opts.Exact {
+							_, r := isRational(q.up[i])
+							exact.Mul(exact, r)
+						}
 						q.up = append(q.up[:i], q.up[i+1:]...)
 						i--
 						amount++
@@ -2451,14 +3547,26 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 				}
 				for i := 0; i < len(q.do); i++ {
 					if ok, n := isNumber(q.do[i]); ok {
+						if n == 0 {
+							return false, nil, s.errorGen(&DivisionByZeroError{
+								Location: Location{Expr: astToStr(a)},
+							})
+						}
 						numbers /= n
+						if s.opts.Exact {
+							_, r := isRational(q.do[i])
+							exact.Quo(exact, r)
+						}
 						q.do = append(q.do[:i], q.do[i+1:]...)
 						i--
 						amount++
 					}
 				}
-				if numbers != 1.0 {
-					q.up = append(q.up, createFloat(numbers))
+				if s.opts.Exact && exact.Cmp(big.NewRat(1, 1)) != 0 {
+					q.up = append(q.up, s.createRat(exact))
+					q.up[0], q.up[len(q.up)-1] = q.up[len(q.up)-1], q.up[0]
+				} else if !s.opts.Exact && numbers != 1.0 {
+					q.up = append(q.up, s.createFloat(numbers))
 					q.up[0], q.up[len(q.up)-1] = q.up[len(q.up)-1], q.up[0]
 				}
 
@@ -2469,9 +3577,8 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 					changed = true
 				}
 				if changed {
-					sortCounter[1]++
 					amountgl++
-					summ[i].value = q.toAst()
+					summ[i].value = s.quoToAst(q)
 				}
 			}
 			if 0 < amountgl {
@@ -2498,7 +3605,6 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 				}
 			}
 			if 0 < amount {
-				sortCounter[2]++
 				return true, summ.toAst(), nil
 			}
 		}
@@ -2519,7 +3625,6 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 					}
 				}
 				if 0 < amount {
-					sortCounter[3]++
 					return true, summ.toAst(), nil
 				}
 			}
@@ -2528,7 +3633,6 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			for i := 1; i < len(summ); i++ {
 				if ok, _ := isNumber(summ[i].value); ok {
 					summ[0], summ[i] = summ[i], summ[0] // swap
-					sortCounter[4]++
 					return true, summ.toAst(), nil
 				}
 			}
@@ -2542,19 +3646,29 @@ func (s *sm) sort(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 func (s *sm) constants(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 	if summ := parseSummArray(a); 1 < len(summ) {
 		var numbers float64 = 0.0
+		exact := new(big.Rat) // for Exact mode
 		amount := 0
 		for i := 0; i < len(summ); i++ {
 			ok, n := isNumber(summ[i].value)
 			if !ok {
 				continue
 			}
+			_, r := isRational(summ[i].value)
 			if summ[i].isNegative {
 				numbers -= n
+				exact.Sub(exact, r)
 			} else {
 				numbers += n
+				exact.Add(exact, r)
+			}
+			if s.opts.Exact {
+				numbers, _ = exact.Float64()
 			}
 			if len(summ) == 1 {
-				return true, createFloat(numbers), nil
+				if s.opts.Exact {
+					return true, s.createRat(exact), nil
+				}
+				return true, s.createFloat(numbers), nil
 			}
 			summ = append(summ[:i], summ[i+1:]...)
 			amount++
@@ -2565,14 +3679,30 @@ func (s *sm) constants(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 			if numbers == 0 {
 				return true, summ.toAst(), nil
 			}
+			result := s.createFloat(numbers)
+			if s.opts.Exact {
+				result = s.createRat(exact)
+			}
 			return true, &goast.BinaryExpr{
-				X:  createFloat(numbers),
+				X:  result,
 				Op: token.ADD,
 				Y:  summ.toAst(),
 			}, nil
 		}
 	}
 
+	if isFraction(a) {
+		// fraction is one number, if it is in lowest terms
+		//
+		// from : 6/4
+		// to   : 3/2
+		_, r := isRational(a)
+		if c := s.createRat(r); astToStr(c) != astToStr(a) {
+			return true, c, nil
+		}
+		return false, nil, nil
+	}
+
 	v, ok := a.(*goast.BinaryExpr)
 	if !ok {
 		return false, nil, nil
@@ -2584,8 +3714,36 @@ func (s *sm) constants(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		return false, nil, nil
 	}
 
+	switch v.Op {
+	case token.ADD, token.SUB, token.MUL, token.QUO:
+	default:
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: astToStr(v)},
+			Msg:      fmt.Sprintf("not supported operation %v", v.Op),
+		})
+	}
+
 	if y == 0.0 && v.Op == token.QUO {
-		return false, nil, s.errorGen(fmt.Errorf("cannot divide by zero"))
+		return false, nil, s.errorGen(&DivisionByZeroError{
+			Location: Location{Expr: astToStr(v)},
+		})
+	}
+
+	if s.opts.Exact {
+		_, x := isRational(v.X)
+		_, y := isRational(v.Y)
+		result := new(big.Rat)
+		switch v.Op {
+		case token.ADD: // +
+			result.Add(x, y)
+		case token.SUB: // -
+			result.Sub(x, y)
+		case token.MUL: // *
+			result.Mul(x, y)
+		case token.QUO: // /
+			result.Quo(x, y)
+		}
+		return true, s.createRat(result), nil
 	}
 
 	var result float64
@@ -2598,35 +3756,171 @@ func (s *sm) constants(a goast.Expr) (changed bool, r goast.Expr, _ error) {
 		result = x * y
 	case token.QUO: // /
 		result = x / y
-	default:
-		panic(v.Op)
 	}
 
-	return true, createFloat(fmt.Sprintf("%.15e", result)), nil
+	return s.parseFloat(fmt.Sprintf("%.15e", result))
 }
 
-// FloatFormat is format of float value, for more precision calculation use
-// value equal 12.
-var FloatFormat int = 3
+// createFloat return number node of value
+func (s *sm) createFloat(value float64) goast.Expr {
+	if s.opts.Exact {
+		// infinity and NaN are not fractions
+		if r, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'g', -1, 64)); ok {
+			return s.createRat(r)
+		}
+	}
+	format := fmt.Sprintf("%%.%df", s.opts.FloatFormat)
+	return &goast.BasicLit{
+		Kind:  token.FLOAT,
+		Value: fmt.Sprintf(format, value),
+	}
+}
 
-func createFloat(value interface{}) *goast.BasicLit {
-	switch v := value.(type) {
-	case float64:
-		format := fmt.Sprintf("%%.%df", FloatFormat)
-		return &goast.BasicLit{
-			Kind:  token.FLOAT,
-			Value: fmt.Sprintf(format, v),
+// createRat return number node of rational value, that is fraction in
+// exact mode
+func (s *sm) createRat(value *big.Rat) goast.Expr {
+	if !s.opts.Exact {
+		f, _ := value.Float64()
+		return s.createFloat(f)
+	}
+	num := &goast.BasicLit{
+		Kind:  token.INT,
+		Value: value.Num().String(),
+	}
+	if value.IsInt() {
+		return num
+	}
+	// from : 1/3
+	// to   : 1 / 3
+	return &goast.BinaryExpr{
+		X:  num,
+		Op: token.QUO,
+		Y: &goast.BasicLit{
+			Kind:  token.INT,
+			Value: value.Denom().String(),
+		},
+	}
+}
+
+// parseFloat return number from string value
+func (s *sm) parseFloat(value string) (changed bool, r goast.Expr, _ error) {
+	value = strings.TrimSpace(value)
+	if s.opts.Exact {
+		r, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return false, nil, s.errorGen(&UnsupportedError{
+				Location: Location{Expr: value},
+				Msg:      "not valid number",
+			})
 		}
-	case int:
-		return createFloat(float64(v))
-	case string:
-		val, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
-		if err != nil {
-			panic(fmt.Errorf("`%s` : %v", value, err))
+		return true, s.createRat(r), nil
+	}
+	val, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false, nil, s.errorGen(&UnsupportedError{
+			Location: Location{Expr: value},
+			Msg:      fmt.Sprintf("not valid number: %v", err),
+		})
+	}
+	return true, s.createFloat(val), nil
+}
+
+// Decimal convert all numbers and fractions of expression into decimals
+// with `prec` digits after point.
+// Example:
+//
+//	expr : "7/3*a + 1"
+//	out  : "2.333*a + 1.000"
+func Decimal(expr string, prec int) (out string, err error) {
+	a, err := parseExpr(expr)
+	if err != nil {
+		return "", err
+	}
+	var conv func(e goast.Expr) goast.Expr
+	conv = func(e goast.Expr) goast.Expr {
+		if isFraction(e) {
+			_, r := isRational(e)
+			return &goast.BasicLit{Kind: token.FLOAT, Value: r.FloatString(prec)}
+		}
+		switch v := e.(type) {
+		case *goast.BasicLit:
+			if ok, r := isRational(v); ok {
+				return &goast.BasicLit{Kind: token.FLOAT, Value: r.FloatString(prec)}
+			}
+		case *goast.BinaryExpr:
+			v.X = conv(v.X)
+			v.Y = conv(v.Y)
+		case *goast.UnaryExpr:
+			v.X = conv(v.X)
+		case *goast.ParenExpr:
+			v.X = conv(v.X)
+		case *goast.CallExpr:
+			for i := range v.Args {
+				v.Args[i] = conv(v.Args[i])
+			}
+		}
+		return e
+	}
+	return external(astToStr(conv(a))), nil
+}
+
+// createNegative return number with opposite sign
+func (s *sm) createNegative(e goast.Expr) goast.Expr {
+	if s.opts.Exact {
+		_, r := isRational(e)
+		return s.createRat(r.Neg(r))
+	}
+	_, n := isNumber(e)
+	return s.createFloat(-n)
+}
+
+// isFraction return true for fraction of integer numbers, like `-1/3`.
+// Fraction is created in Exact mode and must be used as one number.
+func isFraction(node goast.Node) bool {
+	bin, ok := node.(*goast.BinaryExpr)
+	if !ok || bin.Op != token.QUO {
+		return false
+	}
+	isInt := func(e goast.Expr) bool {
+		if un, ok := e.(*goast.UnaryExpr); ok && un.Op == token.SUB {
+			e = un.X
+		}
+		x, ok := e.(*goast.BasicLit)
+		return ok && x.Kind == token.INT
+	}
+	if !isInt(bin.X) || !isInt(bin.Y) {
+		return false
+	}
+	if ok, v := isNumber(bin.Y); !ok || v == 0 {
+		return false
+	}
+	return true
+}
+
+// isRational is exact analog of isNumber
+func isRational(node goast.Node) (ok bool, val *big.Rat) {
+	switch v := node.(type) {
+	case *goast.UnaryExpr:
+		ok, val = isRational(v.X)
+		if ok && v.Op == token.SUB {
+			val.Neg(val)
+		}
+		return ok, val
+	case *goast.ParenExpr:
+		return isRational(v.X)
+	case *goast.BasicLit:
+		if v.Kind == token.INT || v.Kind == token.FLOAT {
+			val, ok = new(big.Rat).SetString(v.Value)
+			return ok, val
+		}
+	case *goast.BinaryExpr:
+		if isFraction(v) {
+			_, x := isRational(v.X)
+			_, y := isRational(v.Y)
+			return true, x.Quo(x, y)
 		}
-		return createFloat(val)
 	}
-	panic(fmt.Errorf("createFloat: %#v", value))
+	return false, nil
 }
 
 func isNumber(node goast.Node) (ok bool, val float64) {
@@ -2640,13 +3934,17 @@ func isNumber(node goast.Node) (ok bool, val float64) {
 	if par, ok := node.(*goast.ParenExpr); ok {
 		return isNumber(par.X)
 	}
+	if isFraction(node) {
+		_, r := isRational(node)
+		val, _ = r.Float64()
+		return true, val
+	}
 	if x, ok := node.(*goast.BasicLit); ok {
 		if x.Kind == token.INT || x.Kind == token.FLOAT {
 			val, err := strconv.ParseFloat(x.Value, 64)
 			if err == nil {
 				return true, val
 			}
-			panic(err)
 		}
 	}
 	return false, 0.0
@@ -2657,10 +3955,10 @@ type matriX struct {
 	Rows, Cols int
 }
 
-func (m matriX) ast() goast.Expr {
+func (s *sm) matrixToAst(m *matriX) goast.Expr {
 	body := append([]goast.Expr{}, m.Args...)
-	body = append(body, createFloat(m.Rows))
-	body = append(body, createFloat(m.Cols))
+	body = append(body, s.createFloat(float64(m.Rows)))
+	body = append(body, s.createFloat(float64(m.Cols)))
 	return &goast.CallExpr{
 		Fun:  goast.NewIdent(matrix),
 		Args: body,
@@ -2691,63 +3989,69 @@ func (m matriX) string() string {
 	return out
 }
 
-func createMatrix(r, c int) (m *matriX) {
+func (s *sm) createMatrix(r, c int) (m *matriX) {
 	m = new(matriX)
 	m.Rows = r
 	m.Cols = c
 	m.Args = make([]goast.Expr, r*c)
 	for pos := range m.Args {
-		m.Args[pos] = createFloat(0)
+		m.Args[pos] = s.createFloat(0)
 	}
 	return
 }
 
-func parseMatrix(str string) (m *matriX, ok bool) {
+func parseMatrix(str string) (m *matriX, ok bool, err error) {
 	expr, err := parser.ParseExpr(str)
 	if err != nil {
-		return nil, false
+		return nil, false, nil
 	}
 	return isMatrix(expr)
 }
 
-func isMatrix(e goast.Expr) (mt *matriX, ok bool) {
+func isMatrix(e goast.Expr) (mt *matriX, ok bool, err error) {
 	call, ok := e.(*goast.CallExpr)
 	if !ok {
-		return nil, false
+		return nil, false, nil
 	}
 	id, ok := call.Fun.(*goast.Ident)
 	if !ok {
-		return nil, false
+		return nil, false, nil
 	}
 	if id.Name != matrix {
-		return nil, false
+		return nil, false, nil
 	}
 	mt = new(matriX)
 	if len(call.Args) < 3 {
-		panic(fmt.Errorf("matrix is not valid: %d\n%#v\n%s", len(call.Args), call, astToStr(call)))
+		return nil, true, &MatrixShapeError{
+			Location: Location{Expr: astToStr(call)},
+			Msg:      "matrix have not values, rows and columns",
+		}
 	}
 	mt.Args = call.Args[:len(call.Args)-2]
 	// parse rows and columns
 	ok, v := isNumber(call.Args[len(call.Args)-2])
 	if !ok {
-		return nil, false
+		return nil, false, nil
 	}
 	mt.Rows = int(v)
 
 	ok, v = isNumber(call.Args[len(call.Args)-1])
 	if !ok {
-		return nil, false
+		return nil, false, nil
 	}
 	mt.Cols = int(v)
 
-	if len(mt.Args) != mt.Rows*mt.Cols {
-		panic(fmt.Errorf("not valid matrix: args=%d rows=%d columns=%d",
-			len(mt.Args),
-			mt.Rows,
-			mt.Cols,
-		))
+	if mt.Rows <= 0 || mt.Cols <= 0 || len(mt.Args) != mt.Rows*mt.Cols {
+		return nil, true, &MatrixShapeError{
+			Location: Location{Expr: astToStr(call)},
+			Msg: fmt.Sprintf("not valid matrix: args=%d rows=%d columns=%d",
+				len(mt.Args),
+				mt.Rows,
+				mt.Cols,
+			),
+		}
 	}
-	return mt, true
+	return mt, true, nil
 }
 
 func isTranspose(e goast.Expr) (ok bool) {
@@ -2774,7 +4078,8 @@ func debug(e goast.Expr) {
 
 type quoArray struct{ up, do []goast.Expr }
 
-func (q quoArray) toAst() goast.Expr {
+func (s *sm) quoToAst(q quoArray) goast.Expr {
+	q = s.fold(q)
 	var upper, downer goast.Expr
 	for i := range q.up {
 		if i == 0 {
@@ -2799,10 +4104,10 @@ func (q quoArray) toAst() goast.Expr {
 		}
 	}
 	if len(q.do) == 0 && len(q.up) == 0 {
-		return createFloat(1)
+		return s.createFloat(1)
 	}
 	if len(q.up) == 0 {
-		upper = createFloat(1)
+		upper = s.createFloat(1)
 	}
 	if len(q.do) == 0 {
 		return upper
@@ -2814,28 +4119,31 @@ func (q quoArray) toAst() goast.Expr {
 	}
 }
 
-func parseQuoArray(e goast.Expr) (q quoArray) {
+func (s *sm) parseQuoArray(e goast.Expr) (q quoArray) {
 	switch v := e.(type) {
 	case *goast.ParenExpr:
-		return parseQuoArray(v.X)
+		return s.parseQuoArray(v.X)
 	case *goast.UnaryExpr:
-		qe := parseQuoArray(v.X)
+		qe := s.parseQuoArray(v.X)
 		if v.Op == token.SUB {
-			qe.up = append(qe.up, createFloat(-1))
+			qe.up = append(qe.up, s.createFloat(-1))
 			qe.up[0], qe.up[len(qe.up)-1] = qe.up[len(qe.up)-1], qe.up[0]
 		}
 		return qe
 	case *goast.BinaryExpr:
 		switch v.Op {
 		case token.MUL:
-			x := parseQuoArray(v.X)
-			y := parseQuoArray(v.Y)
+			x := s.parseQuoArray(v.X)
+			y := s.parseQuoArray(v.Y)
 			q.up = append(x.up, y.up...)
 			q.do = append(x.do, y.do...)
 			return
 		case token.QUO: // x / y
-			x := parseQuoArray(v.X)
-			y := parseQuoArray(v.Y)
+			if isFraction(v) {
+				break
+			}
+			x := s.parseQuoArray(v.X)
+			y := s.parseQuoArray(v.Y)
 			q.up = append(x.up, y.do...)
 			q.do = append(x.do, y.up...)
 			return
@@ -2845,6 +4153,175 @@ func parseQuoArray(e goast.Expr) (q quoArray) {
 	return
 }
 
+// repeated return base and integer exponent of power, like `pow(L,3)`
+func repeated(e goast.Expr) (base goast.Expr, n *big.Int, ok bool) {
+	call, ok := unparen(e).(*goast.CallExpr)
+	if !ok || astToStr(call.Fun) != pow || len(call.Args) != 2 {
+		return nil, nil, false
+	}
+	ok, v := isRational(call.Args[1])
+	if !ok || !v.IsInt() {
+		return nil, nil, false
+	}
+	return call.Args[0], v.Num(), true
+}
+
+// foldable return true for factor, that can be folded into power.
+// Numbers are multiplied and product of matrices is not commutative.
+func foldable(e goast.Expr) bool {
+	e = unparen(e)
+	if ok, _ := isNumber(e); ok {
+		return false
+	}
+	if _, ok, _ := isMatrix(e); ok {
+		return false
+	}
+	if call, ok := e.(*goast.CallExpr); ok {
+		switch astToStr(call.Fun) {
+		case matrix, transpose, inverse:
+			return false
+		}
+	}
+	return true
+}
+
+// hasMatrix return true if any factor is matrix
+func hasMatrix(q quoArray) bool {
+	for _, fs := range [][]goast.Expr{q.up, q.do} {
+		for _, f := range fs {
+			if ok, _ := isNumber(f); !ok && !foldable(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fold return factors with repeated factors folded into power at place
+// of first factor. Factors are kept as base and exponent, exponents of
+// same base are summarized, so factors of numerator and denominator are
+// cancelled.
+//
+//	L*a*L*L = pow(L,3)*a
+//	pow(L,3)*a/L = pow(L,2)*a
+func (s *sm) fold(q quoArray) (r quoArray) {
+	var (
+		lists = [2][]goast.Expr{q.up, q.do}
+		keys  [2][]string
+		order []string
+		base  = map[string]goast.Expr{}
+		exps  = map[string]*big.Int{}
+	)
+	for i := range lists {
+		for _, f := range lists[i] {
+			key, b, n := "", f, big.NewInt(1)
+			if foldable(f) {
+				if rb, rn, ok := repeated(f); ok && foldable(rb) {
+					b, n = rb, new(big.Int).Set(rn)
+				}
+				key = astToStr(unparen(b))
+			}
+			keys[i] = append(keys[i], key)
+			if key == "" {
+				continue
+			}
+			if i == 1 {
+				n.Neg(n)
+			}
+			if _, ok := exps[key]; !ok {
+				order = append(order, key)
+				base[key] = b
+				exps[key] = new(big.Int)
+			}
+			exps[key].Add(exps[key], n)
+		}
+	}
+	// power return factor and index of list by summary exponent
+	power := func(key string) (goast.Expr, int) {
+		n := exps[key]
+		index := 0
+		if n.Sign() < 0 {
+			index = 1
+		}
+		abs := new(big.Int).Abs(n)
+		if abs.Cmp(big.NewInt(1)) == 0 {
+			return base[key], index
+		}
+		return callOf(pow, base[key], s.createRat(new(big.Rat).SetInt(abs))), index
+	}
+	outs := [2]*[]goast.Expr{&r.up, &r.do}
+	for i := range lists {
+		for k, f := range lists[i] {
+			key := keys[i][k]
+			if key == "" {
+				*outs[i] = append(*outs[i], f)
+				continue
+			}
+			if n, ok := exps[key]; !ok || n.Sign() == 0 {
+				continue
+			}
+			if f, index := power(key); index == i {
+				*outs[i] = append(*outs[i], f)
+				delete(exps, key)
+			}
+		}
+	}
+	// factors are moved from numerator to denominator or back
+	for _, key := range order {
+		if n, ok := exps[key]; !ok || n.Sign() == 0 {
+			continue
+		}
+		f, index := power(key)
+		*outs[index] = append(*outs[index], f)
+	}
+	return
+}
+
+// foldPower is folding of repeated factors of product into power
+//
+//	L*(L*(L*a)) = pow(L,3)*a
+//	a/(L*pow(L,2)) = a/pow(L,3)
+func (s *sm) foldPower(a goast.Expr) (changed bool, r goast.Expr, _ error) {
+	bin, ok := a.(*goast.BinaryExpr)
+	if !ok || (bin.Op != token.MUL && bin.Op != token.QUO) || isFraction(bin) {
+		return false, nil, nil
+	}
+	// factors without expanding of powers
+	var factors func(e goast.Expr, up bool)
+	found := map[string]bool{}
+	factors = func(e goast.Expr, up bool) {
+		e = unparen(e)
+		if bin, ok := e.(*goast.BinaryExpr); ok && !isFraction(bin) {
+			switch bin.Op {
+			case token.MUL:
+				factors(bin.X, up)
+				factors(bin.Y, up)
+				return
+			case token.QUO:
+				factors(bin.X, up)
+				factors(bin.Y, !up)
+				return
+			}
+		}
+		if !foldable(e) {
+			return
+		}
+		if base, _, ok := repeated(e); ok && foldable(base) {
+			e = unparen(base)
+		}
+		key := astToStr(e)
+		if found[key] {
+			changed = true
+		}
+		found[key] = true
+	}
+	factors(bin, true)
+	if !changed {
+		return false, nil, nil
+	}
+	return true, s.quoToAst(s.parseQuoArray(bin)), nil
+}
+
 type summSlice []sliceSumm
 
 func (s summSlice) toAst() goast.Expr {
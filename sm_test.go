@@ -1,10 +1,15 @@
 package sm
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var tcs = []struct {
@@ -31,19 +36,19 @@ var tcs = []struct {
 		out:  "10.000 * a",
 	}, {
 		expr: "a*(2+8)*a",
-		out:  "10.000 * a * a",
+		out:  "10.000*pow(a,2.000)",
 	}, {
 		expr: "((a))*(((2+8)))*(a)",
-		out:  "10.000 * a * a",
+		out:  "10.000*pow(a,2.000)",
 	}, {
 		expr: "(2+8)*a",
 		out:  "10.000 * a",
 	}, {
 		expr: "(2+8*a)*a",
-		out:  "2.000*a + 8.000*(a*a)",
+		out:  "2.000*a+8.000*pow(a,2.000)",
 	}, {
 		expr: "b*(2+8*a)*a; constant(a); constant(b)",
-		out:  "2.000*(a*b) + 8.000*(a*(a*b))",
+		out:  "2.000*(a*b)+8.000*(pow(a,2.000)*b)",
 	},
 	{
 		expr: "b*(2+8*a); constant(a); constant(b)",
@@ -75,23 +80,23 @@ var tcs = []struct {
 	},
 	{
 		expr: "pow(a,2)",
-		out:  "a * a",
+		out:  "pow(a,2.000)",
 	},
 	{
 		expr: "pow(a,3)",
-		out:  "a * (a * a)",
+		out:  "pow(a,3.000)",
 	},
 	{
 		expr: "pow(a,-3)",
-		out:  "1.000/(a*(a*a))",
+		out:  "1.000/pow(a,3.000)",
 	},
 	{
 		expr: "pow(a,5-3+1)",
-		out:  "a * (a * a)",
+		out:  "pow(a,3.000)",
 	},
 	{
 		expr: "pow(a+1,2)",
-		out:  "1.000+2.000*a+a*a",
+		out:  "1.000+2.000*a+pow(a,2.000)",
 	},
 	{
 		expr: "pow(a+b,5-4)",
@@ -99,7 +104,7 @@ var tcs = []struct {
 	},
 	{
 		expr: "pow(a+b,4/2); constant(a,b)",
-		out:  "a*a+2.000*(a*b)+b*b",
+		out:  "pow(a,2.000)+2.000*(a*b)+pow(b,2.000)",
 	},
 	{
 		expr: "pow(2,pow(1,-1))",
@@ -118,7 +123,7 @@ var tcs = []struct {
 		// TODO:
 		// true value is 0.0625
 		// formatting error
-		out: "pow(2.000, 0.062)",
+		out: "1.044",
 	},
 	{
 		expr: "pow(9,9)*4*(-3+3)*0+12.3*0-wer*0-0*wed; constant(wer,wed)",
@@ -144,7 +149,7 @@ var tcs = []struct {
 	},
 	{
 		expr: "d(pow(x,3),x);variable(x);",
-		out:  "3.000*x*x",
+		out:  "3.000*pow(x,2.000)",
 	},
 	{
 		expr: "b*d(a*x,x);constant(a);constant(b);variable(x);",
@@ -162,22 +167,45 @@ var tcs = []struct {
 		expr: "d(2*pow(x,a),x);constant(a);variable(x);",
 		out:  "2.000*(a*pow(x,-1.000+a))",
 	},
-	// TODO:
-	// {
-	// 	expr: "d(pow(x,a+1),x);constant(a);variable(x);",
-	// 	out:  "(a+1)*pow(x,a)",
-	// },
+	{
+		expr: "d(pow(x,a+1),x);constant(a);variable(x);",
+		out:  "pow(x, a) + a*pow(x, a)",
+	},
+	{
+		expr: "d(pow(2*x+1,3),x);variable(x)",
+		out:  "6.000+(24.000*x+24.000*pow(x,2.000))",
+	},
+	{
+		expr: "d(pow(x,x),x);variable(x)",
+		out:  "pow(x, x) + pow(x, x)*log(x)",
+	},
+	{
+		expr: "d(pow(2,x),x);variable(x)",
+		out:  "pow(2.000, x) * log(2.000)",
+	},
+	{
+		expr: "d(pow(a,x*x),x);constant(a);variable(x)",
+		out:  "2.000*(log(a)*x)*pow(a,pow(x,2.000))",
+	},
+	{
+		expr: "d(pow(a,b),x);constant(a,b);variable(x)",
+		out:  "0.000",
+	},
+	{
+		expr: "d(pow(u,2),x);function(u,x)",
+		out:  "2.000*u*d(u,x)",
+	},
 	{
 		expr: "d(u*v,x);function(u,x);function(v,x)",
 		out:  "d(u,x)*v + u*d(v,x)",
 	},
 	{
 		expr: "d(u/v,x);function(u,x);function(v,x)",
-		out:  "d(u,x)/v - u*d(v,x) / (v * v)",
+		out:  "d(u,x)/v-u*d(v,x)/pow(v,2.000)",
 	},
 	{
 		expr: "d((2*(3*x-4))/(pow(x,2)+1),x);variable(x);",
-		out:  "6.000/(1.000+2.000*(x*x)+x*(x*(x*x)))+16.000*x/(1.000+2.000*(x*x)+x*(x*(x*x)))-6.000*(x*x)/(1.000+2.000*(x*x)+x*(x*(x*x)))",
+		out:  "6.000/(1.000+2.000*pow(x,2.000)+pow(x,4.000))+16.000*x/(1.000+2.000*pow(x,2.000)+pow(x,4.000))-6.000*pow(x,2.000)/(1.000+2.000*pow(x,2.000)+pow(x,4.000))",
 	},
 	{
 		expr: "d(u + v,x);function(u,x);function(v,x);",
@@ -231,15 +259,15 @@ var tcs = []struct {
 	},
 	{
 		expr: "matrix(5+2*a+a,1,1)*a",
-		out:  "matrix(5.000*a+3.000*(a*a),1.000,1.000)",
+		out:  "matrix(5.000*a+3.000*pow(a,2.000),1.000,1.000)",
 	},
 	{
 		expr: "matrix(5+a,1,1)*a",
-		out:  "matrix(5.000*a+a*a,1.000,1.000)",
+		out:  "matrix(5.000*a+pow(a,2.000),1.000,1.000)",
 	},
 	{
 		expr: "matrix(5+a,4,0,-2*a,2,2)*a",
-		out:  "matrix(5.000*a+a*a,4.000*a,0.000,-2.000*(a*a),2.000,2.000)",
+		out:  "matrix(5.000*a+pow(a,2.000),4.000*a,0.000,-2.000*pow(a,2.000),2.000,2.000)",
 	},
 	// integral
 	{
@@ -331,7 +359,7 @@ var tcs = []struct {
 	},
 	{
 		expr: "integral(a*x*a,x,2,3);constant(a);variable(x)",
-		out:  "2.500*a*a",
+		out:  "2.500*pow(a,2.000)",
 	},
 	{
 		expr: "integral(pow(x,2),x,2,3);variable(x)",
@@ -343,11 +371,11 @@ var tcs = []struct {
 	},
 	{
 		expr: "integral(pow(a*x,3),x,2,3);variable(x);constant(a)",
-		out:  "16.250*(a*a)*a",
+		out:  "16.250*pow(a,3.000)",
 	},
 	{
 		expr: "integral(pow(a*x,2),x,2,3);variable(x);constant(a)",
-		out:  "6.327*a*a",
+		out:  "6.327*pow(a,2.000)",
 	},
 	{
 		expr: "integral(a*pow(x,2),x,2,3);variable(x);constant(a)",
@@ -363,11 +391,11 @@ var tcs = []struct {
 	},
 	{
 		expr: "integral(x*a*x*a*x*a,x,2,3);variable(x);constant(a)",
-		out:  "16.250*(a*a)*a",
+		out:  "16.250*pow(a,3.000)",
 	},
 	{
 		expr: "integral(-1.000/L*(1.000/L), s, 0.000, 1.000); constant(L); variable(s);",
-		out:  "-1.000 / (L * L)",
+		out:  "-1.000/pow(L,2.000)",
 	},
 	{
 		expr: "inject(x*x/2.000, x, 1.000)",
@@ -383,7 +411,7 @@ var tcs = []struct {
 	},
 	{
 		expr: "integral((v*(-1.000/L)+(1.000-s)*sin(q)/r)*(1.000/L), s, 0.000, 1.000); constant(L); constant(q); constant(r);constant(v);variable(s)",
-		out:  "-1.000*v/(L*L)+0.500*sin(q)/(L*r)",
+		out:  "-1.000*v/pow(L,2.000)+0.500*sin(q)/(L*r)",
 	},
 	{
 		expr: `integral(transpose(matrix(a*s,1,1))*matrix(b*s,1,1)*matrix(c*s,1,1),s, 1, 2);variable(s);constant(a);constant(b);constant(c)`,
@@ -395,11 +423,11 @@ var tcs = []struct {
 	},
 	{
 		expr: " integral(s*(6.000/L*(s*(1.000/L))), s, 0.000, 1.000); constant(L);variable(s)",
-		out:  "1.998/(L*L)",
+		out:  "1.998/pow(L,2.000)",
 	},
 	{
 		expr: "integral(1.000/L*(-1.000/L)+v*(1.000/L*((sin(q)-sin(q)*s)/r)), s, 0.000, 1.000);constant(L,v,a,q,r); variable(s)",
-		out:  "-1.000/(L*L)+0.500*(v*sin(q))/(L*r)",
+		out:  "-1.000/pow(L,2.000)+0.500*(v*sin(q))/(L*r)",
 	},
 	{
 		expr: "integral((sin(q)-sin(q)*s)/r*(1.000/L), s, 0.000, 1.000); constant(q,r,L); variable(s)",
@@ -443,7 +471,7 @@ var tcs = []struct {
 	},
 	{
 		expr: `inverse(matrix( 1,l,0, l,0,1, l,1,0, 3,3)); constant(l);`,
-		out:  "matrix(-1.000/(-1.000+l*l),0.000,l/(-1.000+l*l),l/(-1.000+l*l),0.000,-1.000/(-1.000+l*l),l/(-1.000+l*l),1.000,-(l*l/(-1.000+l*l)),3.000,3.000)",
+		out:  "matrix(-1.000/(-1.000+pow(l,2.000)),0.000,l/(-1.000+pow(l,2.000)),l/(-1.000+pow(l,2.000)),0.000,-1.000/(-1.000+pow(l,2.000)),l/(-1.000+pow(l,2.000)),1.000,-(pow(l,2.000)/(-1.000+pow(l,2.000))),3.000,3.000)",
 	},
 	{
 		expr: "l*(l*(1.000/l*(1.000/l*l)))",
@@ -451,47 +479,47 @@ var tcs = []struct {
 	},
 	{
 		expr: "inverse(matrix( 1,0,0,0,0,0, 0,0,1,0,0,0, 0,0,0,1,0,0, 1,l,0,0,0,0, 0,0,1,l,l*l,l*l*l, 0,0,0,1,2*l,3*l*l, 6,6));",
-		out:  "matrix(1.000,0.000,0.000,0.000,0.000,0.000,-1.000/l,0.000,0.000,1.000/l,0.000,0.000,0.000,1.000,0.000,0.000,0.000,0.000,0.000,0.000,1.000,0.000,0.000,0.000,0.000,-3.000/(l*l),-2.000/l,0.000,3.000/(l*l),-1.000/l,0.000,2.000/(l*(l*l)),1.000/(l*l),0.000,-2.000/(l*(l*l)),1.000/(l*l),6.000,6.000)",
+		out:  "matrix(1.000,0.000,0.000,0.000,0.000,0.000,-1.000/l,0.000,0.000,1.000/l,0.000,0.000,0.000,1.000,0.000,0.000,0.000,0.000,0.000,0.000,1.000,0.000,0.000,0.000,0.000,-3.000/pow(l,2.000),-2.000/l,0.000,3.000/pow(l,2.000),-1.000/l,0.000,2.000/pow(l,3.000),1.000/pow(l,2.000),0.000,-2.000/pow(l,3.000),1.000/pow(l,2.000),6.000,6.000)",
 	},
 	{
 		expr: "6.000 / L * (0.333 / L); constant(L)",
-		out:  "1.998 / (L * L)",
+		out:  "1.998/pow(L,2.000)",
 	},
 	{
 		expr: "(l*(1.000/(l*(l*(l*l)))))",
-		out:  "1.000/(l*(l*l))",
+		out:  "1.000/pow(l,3.000)",
 	},
 	{
 		expr: "integral(3.000/(l*(l*(l*l)))*(l*(l*(3.000/(l*(l*(l*l)))*(l*l)))), x, 0.000, l); variable(x)",
-		out:  "9.000/(l*(l*l))",
+		out:  "9.000/pow(l,3.000)",
 	},
 	{
 		expr: "integral(x*(2.000/(l*(l*l))*(3.000/(l*(l*(l*l)))*(l*l))), x, 0.000, l); constant(l);variable(x)",
-		out:  "3.000/(l*(l*l))",
+		out:  "3.000/pow(l,3.000)",
 	},
 	{
 		expr: "36.000/(l*(l*l))*(1.000/(l*l))",
-		out:  "36.000/(l*(l*(l*(l*l))))",
+		out:  "36.000/pow(l,5.000)",
 	},
 	{
 		expr: "3.000/(l*(l*(l*l)))*(l*l)",
-		out:  "3.000/(l*l)",
+		out:  "3.000/pow(l,2.000)",
 	},
 	{
 		expr: "3.000/(l*(l*(l*l)))*(l*l)+1",
-		out:  "1.000 + 3.000/(l*l)",
+		out:  "1.000+3.000/pow(l,2.000)",
 	},
 	{
 		expr: "36.000*EJ/(l*(l*l))+(0.000-72.000*(EJ*integral(x/1.000, x, 0.000, l))/(l*(l*(l*(l*l)))))+(0.000-72.000*(EJ*integral(x/1.000, x, 0.000, l))/(l*(l*(l*(l*l))))+47.952*EJ/(l*(l*l))); variable(x); constant(l);",
-		out:  "11.952*EJ/(l*(l*l))",
+		out:  "11.952*EJ/pow(l,3.000)",
 	},
 	{
 		expr: "0.00+ 1/(36.000/(l*(l*l))*(1.000/(l*l)))-0.00",
-		out:  "0.028*(l*(l*(l*(l*l))))",
+		out:  "0.028*pow(l,5.000)",
 	},
 	{
 		expr: "1/(36.000*EJ/(l*(l*l))+(0.000-72.000*(EJ*integral(x/1.000, x, 0.000, l))/(l*(l*(l*(l*l)))))+(0.000-72.000*(EJ*integral(x/1.000, x, 0.000, l))/(l*(l*(l*(l*l))))+47.952*EJ/(l*(l*l)))); variable(x); constant(l);",
-		out:  "0.084*(l*(l*l))/EJ",
+		out:  "0.084*pow(l,3.000)/EJ",
 	},
 	{
 		expr: "-5*x/y+2*x-0+0+5*y+3*x-1*y+12*x/y+0-0+0-0",
@@ -503,11 +531,11 @@ var tcs = []struct {
 	},
 	{
 		expr: "x*((0.000000-3.000000*(l*l))/(l*(l*(l*l))))",
-		out:  "-3.000*x/(l*l)",
+		out:  "-3.000*x/pow(l,2.000)",
 	},
 	{
 		expr: "-(36.000 * EJ / (l * (l * l))) + 47.952*EJ/(l*(l*l))",
-		out:  "11.952*EJ/(l*(l*l))",
+		out:  "11.952*EJ/pow(l,3.000)",
 	},
 	{
 		expr: `
@@ -531,19 +559,19 @@ var tcs = []struct {
 	},
 	{
 		expr: "2.00000*(l*l)-l*l",
-		out:  "l*l",
+		out:  "pow(l,2.000)",
 	},
 	{
 		expr: "L*L*det(matrix(A*E/L,0,0,0,4*E*J/L+2*P*L/15,-(6*E*J/(L*L)+P/10),0,-2*(6*E*J/(L*L)+P/10),2*(12*E*J/(L*L*L)+6*P/2/L),3,3))",
-		out:  "24.000*(A*(E*(E*(E*(J*J)))))/(L*(L*L))+(24.792*(A*(E*(E*(J*P))))/L+0.778*(A*(E*(L*(P*P)))))",
+		out:  "24.000*(A*(pow(E,3.000)*pow(J,2.000)))/pow(L,3.000)+(24.792*(A*(pow(E,2.000)*(J*P)))/L+0.778*(A*(E*(L*pow(P,2.000)))))",
 	},
 	{
 		expr: "(72.000*(A*(E*(E*(J*(E*J)))))+1.200*(L*(L*(A*(E*(E*(J*P)))))))/(A*(E*(E*(J*(L*(L*L))))))",
-		out:  "72.000*(E*J)/(L*(L*L))+1.200*P/L",
+		out:  "72.000*(E*J)/pow(L,3.000)+1.200*P/L",
 	},
 	{
 		expr: "-11.99952*EJ/(-11.99952*EJ+-144.00000*EJ/(l*l)) + -144.00000*EJ/(-11.99952*(l*(l*EJ))+-144.00000*EJ)",
-		out:  "-12.000*EJ/(-12.000*EJ-144.000*EJ/(l*l))-144.000*EJ/(-12.000*(EJ*(l*l))-144.000*EJ)",
+		out:  "-12.000*EJ/(-12.000*EJ-144.000*EJ/pow(l,2.000))+144.000/(144.000+12.000*pow(l,2.000))",
 	},
 	{
 		expr: "matrix(1,2,3,4,2,2)+matrix(3,4,5,6,2,2)",
@@ -555,11 +583,11 @@ var tcs = []struct {
 	},
 	{
 		expr: "d(-(6.00000*x/(l*l)), x); variable(x); constant(l);",
-		out:  "-6.000 / (l * l)",
+		out:  "-6.000/pow(l,2.000)",
 	},
 	{
 		expr: "integral(pow(0.5*pow(q1*x-q2*x/L+q3*x*x/L/L,2),2),x,0,L);constant(q1,q2,q3,L);variable(x);",
-		out:  "0.050*(L*(L*(L*(L*(L*(q1*(q1*(q1*q1))))))))-0.200*(L*(L*(L*(L*(q1*(q1*(q1*q2)))))))+0.300*(L*(L*(L*(q1*(q1*(q2*q2))))))-0.200*(L*(L*(q1*(q2*(q2*q2)))))+0.050*(L*(q2*(q2*(q2*q2))))+0.167*(L*(L*(L*(L*(q1*(q1*(q1*q3)))))))-0.501*(L*(L*(L*(q1*(q1*(q2*q3))))))+(0.501*(L*(L*(q1*(q2*(q2*q3)))))-0.167*(L*(q2*(q2*(q2*q3))))+(0.214*(L*(L*(L*(q1*(q1*(q3*q3))))))-0.429*(L*(L*(q1*(q2*(q3*q3))))))+(0.214*(L*(q2*(q2*(q3*q3))))+0.125*(L*(L*(q1*(q3*(q3*q3)))))-0.125*(L*(q2*(q3*(q3*q3))))+0.028*(L*(q3*(q3*(q3*q3))))))",
+		out:  "0.050*(pow(L,5.000)*pow(q1,4.000))-0.200*(pow(L,4.000)*(pow(q1,3.000)*q2))+0.300*(pow(L,3.000)*(pow(q1,2.000)*pow(q2,2.000)))-0.200*(pow(L,2.000)*(q1*pow(q2,3.000)))+0.050*(L*pow(q2,4.000))+0.167*(pow(L,4.000)*(pow(q1,3.000)*q3))-0.501*(pow(L,3.000)*(pow(q1,2.000)*(q2*q3)))+(0.501*(pow(L,2.000)*(q1*(pow(q2,2.000)*q3)))-0.167*(L*(pow(q2,3.000)*q3))+(0.214*(pow(L,3.000)*(pow(q1,2.000)*pow(q3,2.000)))-0.429*(pow(L,2.000)*(q1*(q2*pow(q3,2.000)))))+(0.214*(L*(pow(q2,2.000)*pow(q3,2.000)))+0.125*(pow(L,2.000)*(q1*pow(q3,3.000)))-0.125*(L*(q2*pow(q3,3.000)))+0.028*(L*pow(q3,4.000))))",
 	},
 	{
 		expr: "a*(b+c)",
@@ -587,7 +615,487 @@ var tcs = []struct {
 	},
 	{
 		expr: "-18.00000*(EA*(q5*(q5*(q6*q2)))/(L*(L*(L*(L*(L*L))))));constant(q2,q5,q6,L)",
-		out:  "-18.000*(EA*(q2*(q5*(q5*q6)))/(L*(L*(L*(L*(L*L))))))",
+		out:  "-18.000*(EA*(q2*(pow(q5,2.000)*q6))/pow(L,6.000))",
+	},
+	{
+		expr: "d(x^3,x);variable(x)",
+		out:  "3.000*pow(x,2.000)",
+	},
+	{
+		expr: "(a+1)**2",
+		out:  "1.000+2.000*a+pow(a,2.000)",
+	},
+	{
+		expr: "2^-1*a",
+		out:  "0.500 * a",
+	},
+	{
+		expr: "inject(ab*b, b, 2.5e-1);constant(ab)",
+		out:  "0.250 * ab",
+	},
+	{
+		expr: "inject(x*x, x, a+1);constant(a)",
+		out:  "1.000+2.000*a+pow(a,2.000)",
+	},
+	{
+		expr: "subs(x-y, x, y, y, x);variable(x);variable(y)",
+		out:  "y - x",
+	},
+	{
+		expr: "subs(integral(x*a,x,0,a), x, 5, a, 2);variable(x)",
+		out:  "4.000",
+	},
+	{
+		expr: "integral(xx*x, x, 0, 1);constant(xx);variable(x)",
+		out:  "0.500 * xx",
+	},
+	{
+		expr: "d(sin(x),x);variable(x)",
+		out:  "cos(x)",
+	},
+	{
+		expr: "d(pow(x,0.5),x);variable(x)",
+		out:  "0.500*pow(x,-0.500)",
+	},
+	{
+		expr: "d(cos(2*x),x);variable(x)",
+		out:  "-2.000 * sin(2.000*x)",
+	},
+	{
+		expr: "d(tan(x),x);variable(x)",
+		out:  "1.000/pow(cos(x),2.000)",
+	},
+	{
+		expr: "d(exp(a*x),x);constant(a);variable(x)",
+		out:  "a * exp(a*x)",
+	},
+	{
+		expr: "d(log(x*x),x);variable(x)",
+		out:  "2.000 / x",
+	},
+	{
+		expr: "d(sqrt(x),x);variable(x)",
+		out:  "0.500 / sqrt(x)",
+	},
+	{
+		expr: "d(asin(x),x);variable(x)",
+		out:  "1.000/sqrt(1.000-pow(x,2.000))",
+	},
+	{
+		expr: "d(acos(x),x);variable(x)",
+		out:  "-1.000/sqrt(1.000-pow(x,2.000))",
+	},
+	{
+		expr: "d(atan(x),x);variable(x)",
+		out:  "1.000/(1.000+pow(x,2.000))",
+	},
+	{
+		expr: "d(sinh(x),x);variable(x)",
+		out:  "cosh(x)",
+	},
+	{
+		expr: "d(cosh(x),x);variable(x)",
+		out:  "sinh(x)",
+	},
+	{
+		expr: "d(tanh(x),x);variable(x)",
+		out:  "1.000/pow(cosh(x),2.000)",
+	},
+	{
+		expr: "d(sin(q)*x + cos(q),x);constant(q);variable(x)",
+		out:  "sin(q)",
+	},
+	{
+		expr: "d(sin(cos(x)),x);variable(x)",
+		out:  "-1.000 * sin(x) * cos(cos(x))",
+	},
+	{
+		expr: "sin(0)+cos(0)+exp(0)+log(1)+sqrt(1)+tan(0)+acos(1)",
+		out:  "3.000",
+	},
+	{
+		expr: "exp(log(x))+log(exp(y))",
+		out:  "x + y",
+	},
+	{
+		expr: "integral(sin(q)*x,x,0,1);constant(q);variable(x)",
+		out:  "0.500 * sin(q)",
+	},
+	{
+		expr: "d(pow(x,4),x,2);variable(x)",
+		out:  "12.000*pow(x,2.000)",
+	},
+	{
+		expr: "d(x*x*y*y*y,x,y,2);variable(x);variable(y)",
+		out:  "12.000*(x*y)",
+	},
+	{
+		expr: "d(sin(x),x,3);variable(x)",
+		out:  "-1.000 * cos(x)",
+	},
+	{
+		expr: "gradient(x*x*y,x,y);variable(x);variable(y)",
+		out:  "matrix(2.000*(x*y),pow(x,2.000),2.000,1.000)",
+	},
+	{
+		expr: "hessian(x*x*y,x,y);variable(x);variable(y)",
+		out:  "matrix(2.000*y, 2.000*x, 2.000*x, 0.000, 2.000, 2.000)",
+	},
+	{
+		expr: "jacobian(matrix(x*y,x+y,2,1),x,y);variable(x);variable(y)",
+		out:  "matrix(y, x, 1.000, 1.000, 2.000, 2.000)",
+	},
+	{
+		expr: "hessian(0.5*EA/L*pow(q2-q1,2),q1,q2);constant(EA,L);variable(q1);variable(q2)",
+		out:  "matrix(EA/L, -1.000*EA/L, -1.000*EA/L, EA/L, 2.000, 2.000)",
+	},
+	{
+		expr: "integral(a*pow(x,3)+x+1,x);variable(x);constant(a)",
+		out:  "x+(0.500*pow(x,2.000)+0.250*(a*pow(x,4.000)))",
+	},
+	{
+		expr: "integral(1/x,x);variable(x)",
+		out:  "log(abs(x))",
+	},
+	{
+		expr: "integral(pow(x,-3),x);variable(x)",
+		out:  "-0.500/pow(x,2.000)",
+	},
+	{
+		expr: "integral(pow(x,a),x);variable(x);constant(a)",
+		out:  "pow(x, 1.000+a) / (1.000 + a)",
+	},
+	{
+		expr: "integral(sin(2*x+1)+cos(a*x),x);variable(x);constant(a)",
+		out:  "-0.500*cos(1.000+2.000*x) + sin(a*x)/a",
+	},
+	{
+		expr: "integral(exp(-x)+pow(2,x)+1/(2*x+1),x);variable(x)",
+		out:  "-1.000*exp(-x)+(pow(2.000,x)/log(2.000)+0.500*log(abs(1.000+2.000*x)))",
+	},
+	{
+		expr: "integral(log(x)+atan(x),x);variable(x)",
+		out:  "x*log(x)-x+(x*atan(x)-0.500*log(1.000+pow(x,2.000)))",
+	},
+	{
+		expr: "integral(x*x*sin(x),x);variable(x)",
+		out:  "-1.000*(cos(x)*pow(x,2.000))+2.000*(x*sin(x))+2.000*cos(x)",
+	},
+	{
+		expr: "integral(x*log(x),x);variable(x)",
+		out:  "0.500*(pow(x,2.000)*log(x))-0.250*pow(x,2.000)",
+	},
+	{
+		expr: "integral(x*exp(x),x,0,1);variable(x)",
+		out:  "1.000",
+	},
+	{
+		expr: "integral(1/x,x,1,2);variable(x)",
+		out:  "log(2.000)",
+	},
+	{
+		expr: "apart(1/(x*x-1),x);variable(x)",
+		out:  "0.500/(-1.000+x) - 0.500/(1.000+x)",
+	},
+	{
+		expr: "apart((x*x*x+1)/(x*x-3*x+2),x);variable(x)",
+		out:  "3.000 + x - 2.000/(-1.000+x) + 9.000/(-2.000+x)",
+	},
+	{
+		expr: "apart((2*x+3)/(x*x*x+x),x);variable(x)",
+		out:  "3.000/x+(2.000-3.000*x)/(1.000+pow(x,2.000))",
+	},
+	{
+		expr: "apart(x/(a*x+1),x);variable(x);constant(a)",
+		out:  "1.000/a-1.000/(pow(a,2.000)*x+a)",
+	},
+	{
+		expr: "apart(1/((x-a)*(x-b)),x);variable(x);constant(a,b)",
+		out:  "1.000/(a*x-pow(a,2.000)-b*x+a*b)-1.000/(a*x-a*b-b*x+pow(b,2.000))",
+	},
+	{
+		expr: "integral(x/(a*x+1),x);variable(x);constant(a)",
+		out:  "x/a-log(abs(x+1.000/a))/pow(a,2.000)",
+	},
+	{
+		expr: "apart(a/(x*x+x),x);variable(x);constant(a)",
+		out:  "a/x - a/(1.000+x)",
+	},
+	{
+		expr: "integral(1/(x*x+1), x, 0, 1);variable(x)",
+		out:  "atan(1.000)",
+	},
+	{
+		expr: "integral(x/(x*x+2*x+5), x);variable(x)",
+		out:  "0.500*log(5.000+2.000*x+pow(x,2.000))-0.500*atan(0.500+0.500*x)",
+	},
+	{
+		expr: "integral((x*x*x+1)/(x*x-3*x+2), x);variable(x)",
+		out:  "3.000*x+0.500*pow(x,2.000)-2.000*log(abs(-1.000+x))+9.000*log(abs(-2.000+x))",
+	},
+	{
+		expr: "integral(1/x,x,-2,-1);variable(x)",
+		out:  "-log(2.000)",
+	},
+	{
+		expr: "integral(1/(x-3),x,0,1);variable(x)",
+		out:  "log(2.000)-log(3.000)",
+	},
+	{
+		expr: "integral(1/(x*x-4),x,0,1);variable(x)",
+		out:  "-0.250*log(3.000)",
+	},
+	{
+		expr: "integral(sin(x),x,0,a);variable(x)",
+		out:  "1.000-cos(a)",
+	},
+	{
+		expr: "integral(tan(x),x);variable(x)",
+		out:  "-log(abs(cos(x)))",
+	},
+	{
+		expr: "abs(-2)+d(abs(x),x);variable(x)",
+		out:  "2.000+x/abs(x)",
+	},
+	{
+		expr: "integral(1/(x*(x+1)*(x+1)),x);variable(x)",
+		out:  "log(abs(x))-log(abs(1.000+x))+1.000/(1.000+x)",
+	},
+	{
+		expr: "integral(a/(s*s+3*s+2),s);variable(s);constant(a)",
+		out:  "a*log(abs(1.000+s))-a*log(abs(2.000+s))",
+	},
+	{
+		expr: "nintegral(exp(-x*x),x,0,1);variable(x)",
+		out:  "0.747",
+	},
+	{
+		expr: "nintegral(x*x,x,0,3,1e-3);variable(x)",
+		out:  "9.000",
+	},
+	{
+		expr: "a*integral(exp(x)*sin(x)+sqrt(1+x*x*x),x,0,1);variable(x);constant(a)",
+		out:  "2.020 * a",
+	},
+	{
+		expr: "integral2(x*y,x,0,1,y,0,1-x);variable(x);variable(y)",
+		out:  "0.042",
+	},
+	{
+		expr: "integral2(x*y,x,0,1,y,0,1-x,triangle3);variable(x);variable(y)",
+		out:  "0.042",
+	},
+	{
+		expr: "integral2(a*x,x,0,2,y,0,1-x/2,triangle1);variable(x);variable(y);constant(a)",
+		out:  "0.667*a",
+	},
+	{
+		expr: "integral3(x*y*z,x,0,1,y,0,2,z,0,3,gauss1);variable(x);variable(y);variable(z)",
+		out:  "4.500",
+	},
+	{
+		expr: "integral3(x*y*z,x,0,1,y,0,2,z,0,3);variable(x);variable(y);variable(z)",
+		out:  "4.500",
+	},
+	{
+		expr: "integral2(1,x,-1,1,y,0,(1+x)/2,triangle3);variable(x);variable(y)",
+		out:  "1.000",
+	},
+	{
+		expr: "integral(x*x*x,x,0,2,gauss2);variable(x)",
+		out:  "4.000",
+	},
+	{
+		expr: "integral(x*x*x*x,x,-1,1,gauss5);variable(x)",
+		out:  "0.400",
+	},
+	{
+		expr: "integral(matrix(x,a*x*x,2,1),x,0,1,gauss3);variable(x);constant(a)",
+		out:  "matrix(0.500,0.333*a,2.000,1.000)",
+	},
+	{
+		expr: "expand((x+a)*(x+b));variable(x)",
+		out:  "pow(x,2.000)+a*x+b*x+a*b",
+	},
+	{
+		expr: "expand((a+b)*(a-b))",
+		out:  "pow(a,2.000)-pow(b,2.000)",
+	},
+	{
+		expr: "collect(a*x*x+b*x+c*x+d+x*x, x);variable(x)",
+		out:  "(a+1.000)*pow(x,2.000)+(b+c)*x+d",
+	},
+	{
+		expr: "collect(a/x + b/x + c*x, x);variable(x)",
+		out:  "c*x + (a+b)/x",
+	},
+	{
+		expr: "collect(x*sin(x)+a*sin(x), sin(x));variable(x)",
+		out:  "(x + a) * sin(x)",
+	},
+	{
+		expr: "factor(2*x*x*x-2*x);variable(x)",
+		out:  "2.000 * x * (x - 1.000) * (x + 1.000)",
+	},
+	{
+		expr: "factor(6*x*x+5*x+1);variable(x)",
+		out:  "(2.000*x + 1.000) * (3.000*x + 1.000)",
+	},
+	{
+		expr: "factor(a*x+a*y);variable(x);variable(y)",
+		out:  "a * (x + y)",
+	},
+	{
+		expr: "factor(0.5*x*x-0.5);variable(x)",
+		out:  "0.500*(x-1.000)*(x+1.000)",
+	},
+	{
+		expr: "factor(a*a-b*b);constant(a,b)",
+		out:  "(a-b)*(a+b)",
+	},
+	{
+		expr: "factor(4*a*a-9*b*b*c*c);constant(a,b,c)",
+		out:  "(2.000*a-3.000*b*c)*(2.000*a+3.000*b*c)",
+	},
+	{
+		expr: "factor(x*x-(a+b)*x+a*b);variable(x);constant(a,b)",
+		out:  "(x-a)*(x-b)",
+	},
+	{
+		expr: "factor(matrix(x*x-1,2*x,2,1));variable(x)",
+		out:  "matrix((x-1.000)*(x+1.000), 2.000*x, 2.000, 1.000)",
+	},
+	{
+		expr: "d(expand((x+1)*(x+1)),x);variable(x)",
+		out:  "2.000+2.000*x",
+	},
+	{
+		expr: "d(factor(x*x-1),x);variable(x)",
+		out:  "2.000*x",
+	},
+	{
+		expr: "integral(factor(x*x-1),x,0,1);variable(x)",
+		out:  "-0.667",
+	},
+	{
+		expr: "2*collect(x*x+x*a,x);variable(x)",
+		out:  "2.000*pow(x,2.000)+2.000*(a*x)",
+	},
+	{
+		expr: "factor(d(x*x*x,x)-3);variable(x)",
+		out:  "3.000*(x-1.000)*(x+1.000)",
+	},
+	{
+		expr: "coeff((x+a)*(x+b), x, 1);variable(x)",
+		out:  "a + b",
+	},
+	{
+		expr: "2*coeff(a*x*x+b*x*x+c, x, 2);variable(x)",
+		out:  "2.000*a + 2.000*b",
+	},
+	{
+		expr: "(x*x-1)/(x-1);variable(x)",
+		out:  "1.000+x",
+	},
+	{
+		expr: "(x*x*y-y)/(x*y+y);variable(x);variable(y)",
+		out:  "-1.000+x",
+	},
+	{
+		expr: "matrix((L*L-4*a*a)/(L-2*a),L/(L*L+L),1,2);constant(L);constant(a)",
+		out:  "matrix(L+2.000*a,1.000/(1.000+L),1.000,2.000)",
+	},
+	{
+		expr: "gcd(x*x-1, x*x+2*x+1);variable(x)",
+		out:  "1.000+x",
+	},
+	{
+		expr: "gcd(2*x*x-2, 4*x+4);variable(x)",
+		out:  "2.000+2.000*x",
+	},
+	{
+		expr: "gcd((x+y)*(x-y)*(x+1), (x+y)*(x+2));variable(x);variable(y)",
+		out:  "x+y",
+	},
+	{
+		expr: "cancel(x*x/(x-1) - 1/(x-1));variable(x)",
+		out:  "1.000+x",
+	},
+	{
+		expr: "L*(L*(L*(L*(L*L))));constant(L)",
+		out:  "pow(L, 6.000)",
+	},
+	{
+		expr: "a/(L*L*L);constant(L)",
+		out:  "a / pow(L, 3.000)",
+	},
+	{
+		expr: "pow(L,3)/L*a/(L*pow(L,-2));constant(L)",
+		out:  "pow(L, 3.000) * a",
+	},
+	{
+		expr: "pow(L,2)*a*pow(L,-2)*L;constant(L)",
+		out:  "L * a",
+	},
+	{
+		expr: "factor(x*x*x-3*x*x+3*x-1);variable(x)",
+		out:  "pow(x-1.000, 3.000)",
+	},
+	{
+		expr: "pow(x,100000000)*x/pow(x,3)*a;variable(x);constant(a)",
+		out:  "a * pow(x, 99999998.000)",
+	},
+	{
+		expr: "pow(a*b,100000000)/b;constant(a,b)",
+		out:  "pow(a, 100000000.000) * pow(b, 99999999.000)",
+	},
+	{
+		expr: "pow(pow(L,2),3)*L;constant(L)",
+		out:  "pow(L, 7.000)",
+	},
+	{
+		expr: "d(2x(x+1),x);variable(x)",
+		out:  "2.000 + 4.000*x",
+	},
+	{
+		expr: "a(b+c);constant(a,b,c)",
+		out:  "a*b + a*c",
+	},
+	{
+		expr: "pow (a,2) + d (x*x,x) + sin (x)*2;variable(x)",
+		out:  "pow(a,2.000)+2.000*x+2.000*sin(x)",
+	},
+	{
+		expr: "u (x)*2;function(u,x);variable(x)",
+		out:  "2.000*u(x)",
+	},
+	{
+		expr: "subs(integral(x,x,0,a,gauss2),gauss2,3);variable(x);constant(a)",
+		out:  "0.500 * pow(a, 2.000)",
+	},
+	{
+		expr: "integral(x*x*x,x,a,b,gauss2);variable(x);constant(a,b)",
+		out:  "0.250*pow(b, 4.000) - 0.250*pow(a, 4.000)",
+	},
+	{
+		expr: "apart(x*x+1,x)+apart(3,x);variable(x)",
+		out:  "4.000 + pow(x, 2.000)",
+	},
+	{
+		expr: "apart((x*x*x+1)/(x*x+x),x);variable(x)",
+		out:  "-1.000 + (1.000/x + x)",
+	},
+	{
+		expr: "integral(1/sqrt(x),x,0,1);variable(x)",
+		out:  "2.000",
+	},
+	{
+		expr: "d(λ x₁^2, x₁);constant(λ);variable(x₁)",
+		out:  "2.000 * (λ * x_1)",
+	},
+	{
+		expr: "d(u'*x^2,x);constant(u');variable(x)",
+		out:  "2.000 * (u' * x)",
 	},
 	// 		{
 	// 			expr: `d(0.50000*(q1*(q1*EA))/L - 0.50000*(q1*(q4*EA))/L - 0.50000*(q1*(EA*q4))/L + 0.50000*(q4*(q4*EA))/L - 12.00000*(q1*(EA*(q3*q2)))/(L*(L*L)) + 18.00000*(q2*(q5*(q1*EA)))/(L*(L*(L*L))) - 6.00000*(q1*(q6*(EA*q2)))/(L*(L*L)) - 12.00000*(q1*(q2*(EA*q3)))/(L*(L*L)) - 8.00000*(q1*(EA*(q3*q3)))/(L*L) + 12.00000*(q3*(q5*(q1*EA)))/(L*(L*L)) - 4.00000*(q1*(q6*(EA*q3)))/(L*L) + 12.00000*(q5*(EA*(q1*q3)))/(L*(L*L)) - 18.00000*(q1*(q5*(EA*q5)))/(L*(L*(L*L))) + 6.00000*(q5*(q6*(q1*EA)))/(L*(L*L)) - 6.00000*(q1*(q2*(EA*q6)))/(L*(L*L)) - 4.00000*(q1*(EA*(q3*q6)))/(L*L) + 6.00000*(q6*(q5*(q1*EA)))/(L*(L*L)) - 2.00000*(EA*(q1*(q6*q6)))/(L*L) + 12.00000*(q3*(q2*(q4*EA)))/(L*(L*L)) - 18.00000*(q4*(EA*(q2*q5)))/(L*(L*(L*L))) + 6.00000*(EA*(q2*(q4*q6)))/(L*(L*L)) + 8.00000*(q3*(q3*(q4*EA)))/(L*L) - 12.00000*(q4*(EA*(q3*q5)))/(L*(L*L)) + 4.00000*(EA*(q3*(q4*q6)))/(L*L) - 18.00000*(q4*(EA*(q5*q2)))/(L*(L*(L*L))) - 12.00000*(q4*(q3*(q5*EA)))/(L*(L*L)) + 18.00000*(EA*(q5*(q4*q5)))/(L*(L*(L*L))) - 6.00000*(q4*(EA*(q5*q6)))/(L*(L*L)) + 4.00000*(q3*(q6*(q4*EA)))/(L*L) - 6.00000*(q4*(EA*(q6*q5)))/(L*(L*L)) + 18.00000*(EA*(q2*(q1*q3)))/(L*(L*L)) - 36.00000*(EA*(q5*(q2*q1)))/(L*(L*(L*L))) + 18.00000*(EA*(q2*(q1*q6)))/(L*(L*L)) + 24.00000*(q3*(q2*(q1*EA)))/(L*(L*L)) + 12.00000*(EA*(q3*(q1*q3)))/(L*L) - 24.00000*(EA*(q5*(q3*q1)))/(L*(L*L)) + 12.00000*(EA*(q3*(q1*q6)))/(L*L) - 36.00000*(q2*(EA*(q5*q1)))/(L*(L*(L*L))) - 18.00000*(q5*(q3*(EA*q1)))/(L*(L*L)) + 36.00000*(q5*(EA*(q1*q5)))/(L*(L*(L*L))) - 18.00000*(q5*(q6*(EA*q1)))/(L*(L*L)) + 12.00000*(q6*(q2*(q1*EA)))/(L*(L*L)) + 6.00000*(q1*(q6*(q3*EA)))/(L*L) - 12.00000*(EA*(q5*(q6*q1)))/(L*(L*L)) + 6.00000*(q1*(q6*(q6*EA)))/(L*L) - 36.00000*(q2*(EA*(q2*q4)))/(L*(L*(L*L))) - 18.00000*(q3*(EA*(q2*q4)))/(L*(L*L)) - 18.00000*(q6*(EA*(q2*q4)))/(L*(L*L)) - 24.00000*(EA*(q3*(q2*q4)))/(L*(L*L)) - 12.00000*(q3*(EA*(q3*q4)))/(L*L) + 24.00000*(q5*(q3*(q4*EA)))/(L*(L*L)) - 12.00000*(q6*(EA*(q3*q4)))/(L*L) + 36.00000*(EA*(q5*(q4*q2)))/(L*(L*(L*L))) - 36.00000*(q5*(q5*(EA*q4)))/(L*(L*(L*L))) - 12.00000*(EA*(q6*(q2*q4)))/(L*(L*L)) - 6.00000*(q6*(q3*(EA*q4)))/(L*L) + 12.00000*(q5*(q6*(q4*EA)))/(L*(L*L)) - 6.00000*(q6*(q6*(EA*q4)))/(L*L) - 5.99976*(EA*(q2*(q1*q2)))/(L*(L*(L*L))) - 11.99988*(q3*(EA*(q1*q2)))/(L*(L*L)) + 23.99976*(q2*(EA*(q1*q5)))/(L*(L*(L*L))) - 11.99988*(q6*(EA*(q1*q2)))/(L*(L*L)) - 11.99988*(EA*(q3*(q1*q2)))/(L*(L*L)) - 5.99994*(q3*(q3*(EA*q1)))/(L*L) + 11.99988*(q3*(EA*(q1*q5)))/(L*(L*L)) - 5.99994*(q3*(q6*(EA*q1)))/(L*L) + 41.99976*(q5*(q2*(q1*EA)))/(L*(L*(L*L))) + 11.99988*(EA*(q5*(q1*q3)))/(L*(L*L)) - 23.99976*(q5*(q5*(q1*EA)))/(L*(L*(L*L))) + 11.99988*(EA*(q5*(q1*q6)))/(L*(L*L)) - 11.99988*(EA*(q6*(q1*q2)))/(L*(L*L)) - 5.99994*(q6*(q3*(EA*q1)))/(L*L) + 11.99988*(q6*(EA*(q1*q5)))/(L*(L*L)) - 5.99994*(q6*(q6*(EA*q1)))/(L*L) + 41.99976*(EA*(q2*(q4*q2)))/(L*(L*(L*L))) + 11.99988*(q3*(EA*(q4*q2)))/(L*(L*L)) - 23.99976*(q2*(EA*(q4*q5)))/(L*(L*(L*L))) + 11.99988*(q6*(EA*(q4*q2)))/(L*(L*L)) + 23.99988*(EA*(q3*(q4*q2)))/(L*(L*L)) + 5.99994*(q4*(q3*(q3EA*q1/L-EA*q4/L+24.000*(EA*(q3*q5))/(L*(L*L))+6.000*(EA*(q2*q3))/(L*(L*L))+12.000*(EA*(q2*q6))/(L*(L*L))-6.000*(EA*(q2*q2))/(L*(L*(L*L)))-12.000*(EA*(q3*q2))/(L*(L*L))+6.000*(EA*(q2*q5))/(L*(L*(L*L)))-2.000*(EA*(q3*q3))/(L*L)+2.000*(EA*(q3*q6))/(L*L)+6.000*(EA*(q5*q2))/(L*(L*(L*L)))-18.000*(EA*(q5*q3))/(L*(L*L))-6.000*(EA*(q5*q5))/(L*(L*(L*L)))-12.000*(EA*(q5*q6))/(L*(L*L))-18.000*(EA*(q6*q2))/(L*(L*L))-4.000*(EA*(q6*q3))/(L*L)+18.000*(EA*(q6*q5))/(L*(L*L))-2.000*(EA*(q6*q6))/(L*L)+EA*q1/L-EA*q4/L+24.000*(EA*(q3*q5))/(L*(L*L))+6.000*(EA*(q2*q3))/(L*(L*L))+12.000*(EA*(q2*q6))/(L*(L*L))-6.000*(EA*(q2*q2))/(L*(L*(L*L)))-12.000*(EA*(q3*q2))/(L*(L*L))+6.000*(EA*(q2*q5))/(L*(L*(L*L)))-2.000*(EA*(q3*q3))/(L*L)+2.000*(EA*(q3*q6))/(L*L)+6.000*(EA*(q5*q2))/(L*(L*(L*L)))-18.000*(EA*(q5*q3))/(L*(L*L))-6.000*(EA*(q5*q5))/(L*(L*(L*L)))-12.000*(EA*(q5*q6))/(L*(L*L))-18.000*(EA*(q6*q2))/(L*(L*L))-4.000*(EA*(q6*q3))/(L*L)+18.000*(EA*(q6*q5))/(L*(L*L))-2.000*(EA*(q6*q6))/(L*L)*EA)))/(L*L) + 6.00012*(q3*(EA*(q4*q5)))/(L*(L*L)) + 5.99994*(q4*(q3*(q6*EA)))/(L*L) + 12.00024*(q5*(q2*(q4*EA)))/(L*(L*(L*L))) - 11.99988*(EA*(q5*(q4*q3)))/(L*(L*L)) + 23.99976*(q5*(q5*(q4*EA)))/(L*(L*(L*L))) - 11.99988*(EA*(q5*(q4*q6)))/(L*(L*L)) + 17.99988*(EA*(q6*(q4*q2)))/(L*(L*L)) + 5.99994*(q4*(q6*(q3*EA)))/(L*L) + 6.00012*(q6*(EA*(q4*q5)))/(L*(L*L)) + 7.99994*(q4*(q6*(q6*EA)))/(L*L) + (54.00000*(q2*(q2*(q6*(q2*EA))))/(L*(L*(L*(L*(L*L))))) + 36.00000*(q3*(q2*(q6*(q2*EA))))/(L*(L*(L*(L*L)))) - 162.00000*(q2*(q2*(EA*(q5*q2))))/(L*(L*(L*(L*(L*(L*L)))))) - 108.00000*(q2*(q2*(q3*(q5*EA))))/(L*(L*(L*(L*(L*L))))) - 54.00000*(q2*(q2*(EA*(q5*q6))))/(L*(L*(L*(L*(L*L))))) + 36.00000*(q2*(q3*(q6*(q2*EA))))/(L*(L*(L*(L*L)))) + 24.00000*(q3*(q3*(q6*(q2*EA))))/(L*(L*(L*L))) - 108.00000*(q3*(q2*(EA*(q5*q2))))/(L*(L*(L*(L*(L*L))))) + 108.00000*(q5*(q3*(q5*(q2*EA))))/(L*(L*(L*(L*(L*L))))) - 36.00000*(q3*(q2*(EA*(q5*q6))))/(L*(L*(L*(L*L)))) + 162.00000*(q5*(q5*(EA*(q2*q2))))/(L*(L*(L*(L*(L*(L*L)))))) + 108.00000*(q5*(q5*(EA*(q2*q3))))/(L*(L*(L*(L*(L*L))))) + 108.00000*(EA*(q5*(q3*(q2*q5))))/(L*(L*(L*(L*(L*L))))) + 54.00000*(q6*(q5*(EA*(q2*q5))))/(L*(L*(L*(L*(L*L))))) + 54.00000*(q5*(q5*(EA*(q2*q6))))/(L*(L*(L*(L*(L*L))))) + 18.00000*(q2*(q6*(q6*(q2*EA))))/(L*(L*(L*(L*L))))),q1);constant(EA,EJ,L,x); variable(q1)`,
@@ -626,13 +1134,356 @@ func Test(t *testing.T) {
 				t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, tcs[i].out)
 			}
 
-			// TODO:
-			// check by inject numbers
-			// create list of constants
-			// replace constants into number and get the result
-			// simplify expression
-			// replace constants into number and get the result
-			// comparing diff of results
+			// check by inject numbers, derivatives of declared functions
+			// cannot be calculated
+			if strings.Contains(tcs[i].expr, "function(") {
+				return
+			}
+			if err = CheckEquivalent(tcs[i].expr, act, 1e-2); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestExact(t *testing.T) {
+	for i, tc := range []struct {
+		expr string
+		out  string
+	}{
+		{
+			expr: "1/3+1/6",
+			out:  "1/2",
+		},
+		{
+			expr: "0.5*a+a/4",
+			out:  "3/4*a",
+		},
+		{
+			expr: "integral(pow(x,2),x,1,2);variable(x)",
+			out:  "7/3",
+		},
+		{
+			expr: "6.000 / L * (1/3 / L); constant(L)",
+			out:  "2/pow(L,2)",
+		},
+		{
+			expr: "det(matrix(-1,1.5,1,-1,2,2))",
+			out:  "-1/2",
+		},
+		{
+			expr: "d(pow(x,0.5),x);variable(x)",
+			out:  "1/2*pow(x,-1/2)",
+		},
+		{
+			expr: "inverse(matrix(1,2,3,4,2,2))",
+			out:  "matrix(-2,1,3/2,-1/2,2,2)",
+		},
+		{
+			expr: "pow(8/27,2/3)*pow(4,-1/2)+pow(2,1/2)",
+			out:  "2/9+pow(2,1/2)",
+		},
+		{
+			expr: "2/4",
+			out:  "1/2",
+		},
+		{
+			expr: "6/4",
+			out:  "3/2",
+		},
+		{
+			expr: "4/2*a",
+			out:  "2*a",
+		},
+		{
+			expr: "-6/(-4)*a",
+			out:  "3/2*a",
+		},
+		{
+			expr: "integral(exp(-x*x),x,0,1);variable(x)",
+			out:  "0.747",
+		},
+		{
+			expr: "2*integral(exp(-x*x),x,0,1)+1/3;variable(x)",
+			out:  "1/3+2*0.747",
+		},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			act, err := SexprWithOptions(context.Background(), nil, tc.expr, Options{Exact: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			act = strings.Replace(act, " ", "", -1)
+			if act != tc.out {
+				t.Fatalf("Is not same \nActual : '%s'\nExpect : '%s'", act, tc.out)
+			}
+		})
+	}
+
+	dec, err := Decimal("7/3*a+matrix(-1/2,1,1)", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "2.333*a + matrix(-0.500, 1.000, 1.000)"; dec != exp {
+		t.Fatalf("Decimal is not same \nActual : '%s'\nExpect : '%s'", dec, exp)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	expr := "integral(pow(x,2),x,0,1)+a/3;variable(x)"
+	var wg sync.WaitGroup
+	for _, tc := range []struct {
+		opts Options
+		out  string
+	}{
+		{opts: Options{}, out: "0.333+0.333*a"},
+		{opts: Options{FloatFormat: 5}, out: "0.33333+0.33333*a"},
+		{opts: Options{FloatFormat: 12}, out: "0.333333333333+0.333333333333*a"},
+		{opts: Options{Exact: true}, out: "1/3+1/3*a"},
+	} {
+		wg.Add(1)
+		go func(opts Options, out string) {
+			defer wg.Done()
+			act, err := SexprWithOptions(context.Background(), nil, expr, opts)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			act = strings.Replace(act, " ", "", -1)
+			if act != out {
+				t.Errorf("Is not same \nActual : '%s'\nExpect : '%s'", act, out)
+			}
+		}(tc.opts, tc.out)
+	}
+	wg.Wait()
+
+	t.Run("MaxIteration", func(t *testing.T) {
+		_, err := SexprWithOptions(context.Background(), nil, expr, Options{MaxIteration: 5})
+		if err == nil {
+			t.Fatalf("iteration limit is ignored")
+		}
+	})
+	t.Run("Trace", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := SexprWithOptions(context.Background(), nil, expr, Options{Trace: &buf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "> rule = ") {
+			t.Fatalf("trace is empty")
+		}
+	})
+	t.Run("OnStep", func(t *testing.T) {
+		var steps []Step
+		_, err := SexprWithOptions(context.Background(), nil, expr, Options{
+			OnStep: func(st Step) {
+				steps = append(steps, st)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules := map[string]bool{}
+		for _, st := range steps {
+			if st.Before == st.After {
+				t.Errorf("step without changes: %#v", st)
+			}
+			rules[st.Rule] = true
+		}
+		for _, name := range []string{"integral", "inject", "constants"} {
+			if !rules[name] {
+				t.Errorf("rule `%s` is not found in steps: %v", name, rules)
+			}
+		}
+	})
+	t.Run("Estimate", func(t *testing.T) {
+		var estimate float64
+		_, err := SexprWithOptions(context.Background(), nil, "integral(exp(-x*x),x,0,1);variable(x)", Options{
+			Exact: true,
+			OnStep: func(st Step) {
+				if st.Rule == "quadrature" {
+					estimate = st.Estimate
+				}
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate <= 0 || 1e-9 < estimate {
+			t.Fatalf("not valid estimate: %v", estimate)
+		}
+	})
+}
+
+type cancelWriter struct {
+	cancel context.CancelFunc
+}
+
+func (c cancelWriter) Write(p []byte) (int, error) {
+	c.cancel()
+	return len(p), nil
+}
+
+func TestContext(t *testing.T) {
+	expr := "integral(pow(x,2),x,0,1)+d(pow(x,3),x)*a;variable(x)"
+	check := func(t *testing.T, err error, ctxErr error) {
+		ce, ok := err.(*CancelError)
+		if !ok {
+			t.Fatalf("not cancel error: %v", err)
+		}
+		if ce.Err != ctxErr {
+			t.Fatalf("not same context error: %v", ce.Err)
+		}
+		if ce.Expr == "" {
+			t.Fatalf("partial expression is empty")
+		}
+		if _, err := Sexpr(nil, ce.Expr+";variable(x)"); err != nil {
+			t.Fatalf("partial expression is not valid: %v", err)
+		}
+	}
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		// cancel after first intermediate result
+		_, err := SexprContext(ctx, cancelWriter{cancel: cancel}, expr)
+		check(t, err, context.Canceled)
+	})
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+		_, err := SexprContext(ctx, nil, expr)
+		check(t, err, context.DeadlineExceeded)
+	})
+	t.Run("Simplify", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		e, err := Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = SimplifyContext(ctx, e, Options{Out: cancelWriter{cancel: cancel}})
+		check(t, err, context.Canceled)
+	})
+}
+
+func TestErrors(t *testing.T) {
+	for i, tc := range []struct {
+		expr   string
+		target interface{}
+		sub    string
+	}{
+		{"1+det(matrix(1,2,3,4,5,6,2,3))", new(*MatrixShapeError), "det("},
+		{"matrix(1,2,3,2,2)+matrix(1,2,3,4,2,2)", new(*MatrixShapeError), "matrix("},
+		{"a+inverse(matrix(1,2,2,1))", new(*MatrixShapeError), "inverse("},
+		{"transpose(matrix(1,2,1,2),1)", new(*ArityError), "transpose("},
+		{"integral(x,x,0);variable(x)", new(*ArityError), "integral("},
+		{"a*d(x);variable(x);constant(a)", new(*ArityError), "d("},
+		{"a*pow(x,2,3);variable(x);constant(a)", new(*ArityError), "pow("},
+		{"a*d(sin(x,2),x);variable(x);constant(a)", new(*ArityError), "sin("},
+		{"a*d(x,x,0);variable(x);constant(a)", new(*UnsupportedError), "0"},
+		{"a*gradient(x);variable(x);constant(a)", new(*ArityError), "gradient("},
+		{"a*hessian(matrix(x,1,1),x);variable(x);constant(a)", new(*UnsupportedError), "hessian("},
+		{"a*subs(x,x,1,x);constant(a)", new(*ArityError), "subs("},
+		{"a*subs(x,2,1);constant(a)", new(*UnsupportedError), "2"},
+		{"a*apart(1/(x+1));variable(x);constant(a)", new(*ArityError), "apart("},
+		{"a*apart(1/(x+1),2);variable(x);constant(a)", new(*UnsupportedError), "apart("},
+		{"a*apart(1/((x*x+1)*(x*x+1)),x);variable(x);constant(a)", new(*UnsupportedError), "apart("},
+		{"a*apart(1/((x-a)*(x-a)),x);variable(x);constant(a)", new(*UnsupportedError), "apart("},
+		{"a*nintegral(x,x,0);variable(x);constant(a)", new(*ArityError), "nintegral("},
+		{"a*nintegral(a*x,x,0,1);variable(x);constant(a)", new(*UnsupportedError), "a"},
+		{"a*nintegral(1/x,x,0,1);variable(x);constant(a)", new(*UnsupportedError), "nintegral("},
+		{"a*integral(exp(1/x),x,0,1);variable(x);constant(a)", new(*UnsupportedError), "nintegral("},
+		{"a*integral2(x,x,0,1,y,0);variable(x);variable(y);constant(a)", new(*ArityError), "integral2("},
+		{"a*collect(x*x);variable(x);constant(a)", new(*ArityError), "collect("},
+		{"a*collect(x*x,2);variable(x);constant(a)", new(*UnsupportedError), "collect("},
+		{"a*coeff(x*x,x);variable(x);constant(a)", new(*ArityError), "coeff("},
+		{"a*coeff(x*x,x,0.5);variable(x);constant(a)", new(*UnsupportedError), "coeff("},
+		{"a*gcd(x*x);variable(x);constant(a)", new(*ArityError), "gcd("},
+		{"a*gcd(x*x,1/x);variable(x);constant(a)", new(*UnsupportedError), "gcd("},
+		{"a*cancel(x,x);variable(x);constant(a)", new(*ArityError), "cancel("},
+		{"a*integral(x,x,0,1,gauss9);variable(x);constant(a)", new(*UnsupportedError), "integral("},
+		{"a*integral(x,x,0,1,triangle3);variable(x);constant(a)", new(*UnsupportedError), "integral("},
+		{"a*integral2(x,x,0,1,y,0,1,triangle3);variable(x);variable(y);constant(a)", new(*UnsupportedError), "integral2("},
+		{"a*integral3(x,x,0,1,y,0,1,z,0,1,triangle3);variable(x);variable(y);variable(z);constant(a)", new(*UnsupportedError), "integral3("},
+		{"a+1/0;constant(a)", new(*DivisionByZeroError), "1"},
+		{"a+matrix(1,2,3,2,2);constant(a)", new(*MatrixShapeError), "matrix("},
+		{"matrix(1,2,3,4,2,2)*matrix(1,2,3,3,1)", new(*MatrixShapeError), "matrix("},
+		{"a/(2-2);constant(a)", new(*DivisionByZeroError), "a"},
+		{"a/matrix(1,2,1,2);constant(a)", new(*UnsupportedError), "a"},
+		{"a+det(b);constant(a,b)", new(*UnsupportedError), "det("},
+		{"a+5%2;constant(a)", new(*SyntaxError), "a+5%2"},
+		{"a[1]+2;constant(a)", new(*SyntaxError), "a[1]"},
+		{"2+a(b,c);constant(a,b,c)", new(*SyntaxError), "a(b,c)"},
+		{"a*integral(1/x,x,-1,1);variable(x);constant(a)", new(*UnsupportedError), "integral("},
+		{"a*integral(1/(x*x-2),x,0,2);variable(x);constant(a)", new(*UnsupportedError), "integral("},
+		{"a*integral(log(x),x,-1,1);variable(x);constant(a)", new(*UnsupportedError), "integral("},
+		{"a+1e400;constant(a)", new(*UnsupportedError), "1e400"},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			_, err := Sexpr(nil, tc.expr)
+			if err == nil {
+				t.Fatalf("error is not found")
+			}
+			if _, ok := err.(*Error); !ok {
+				t.Fatalf("not wrapped error: %T", err)
+			}
+			if !errors.As(err, tc.target) {
+				t.Fatalf("not valid type of error: %v", err)
+			}
+			var l Location
+			switch e := tc.target.(type) {
+			case **MatrixShapeError:
+				l = (*e).Location
+			case **ArityError:
+				l = (*e).Location
+			case **DivisionByZeroError:
+				l = (*e).Location
+			case **UnsupportedError:
+				l = (*e).Location
+			case **SyntaxError:
+				l = (*e).Location
+			}
+			if !strings.HasPrefix(strings.Replace(l.Expr, " ", "", -1), tc.sub) {
+				t.Errorf("not valid sub-expression: %s", l.Expr)
+			}
+			if l.Pos < 0 {
+				t.Errorf("position is not found: %v", err)
+			}
+		})
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	for i, tc := range []struct {
+		expr    string
+		segment int
+		column  int
+	}{
+		{"a+1/0;constant(a)", 0, 3},
+		{"a*d(x) ; variable(x);constant(a)", 0, 3},
+		{"x; constant(a, 1)", 1, 14},
+		{"x;variable(x,y)", 1, 1},
+		{"a + (b", 0, 7},
+		{"constant(λ); 2λ + y^", 1, 10},
+		{"constant(a); a+inverse(matrix(1,2,2,1))", 1, 4},
+	} {
+		t.Run(fmt.Sprintf("%d:%s", i, tc.expr), func(t *testing.T) {
+			_, err := Sexpr(nil, tc.expr)
+			if err == nil {
+				t.Fatalf("error is not found")
+			}
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("not wrapped error: %T", err)
+			}
+			l, ok := e.Err.(interface{ location() *Location })
+			if !ok {
+				t.Fatalf("error without location: %T", e.Err)
+			}
+			if seg, col := l.location().Segment, l.location().Column; seg != tc.segment || col != tc.column {
+				t.Fatalf("not valid position: segment %d, column %d\n%v", seg, col, err)
+			}
+			if !strings.Contains(err.Error(), "^") {
+				t.Fatalf("caret is not found:\n%v", err)
+			}
 		})
 	}
 }